@@ -0,0 +1,177 @@
+// Package action keeps the commands giopad can run, so that key bindings,
+// the command palette and buttons all reach them the same way.
+package action
+
+import (
+	"slices"
+	"time"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+)
+
+// sequenceTimeout is how long a half-typed key sequence waits for its
+// next chord
+const sequenceTimeout = 2 * time.Second
+
+// Action is one command: a stable ID for key maps and settings, a title
+// for the palette and the keys that run it by default
+type Action struct {
+	ID     string
+	Title  string
+	Keys   []Binding
+	When   func() bool // Optional; the action is ignored while it returns false
+	Hidden bool        // Kept out of the palette, like tree cursor moves
+	Run    func()
+}
+
+// Enabled returns true if the action can run right now
+func (a *Action) Enabled() bool {
+	return a.When == nil || a.When()
+}
+
+// bound ties an effective key binding to its action
+type bound struct {
+	keys   Binding
+	action *Action
+}
+
+// Registry holds the registered actions in registration order, and the
+// bindings that run them: the defaults, overridden by a keymap
+type Registry struct {
+	actions  []*Action
+	byID     map[string]*Action
+	keymap   []KeymapEntry
+	bindings []bound
+
+	pending   Binding // chords typed so far of a longer sequence
+	pendingAt time.Time
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{byID: make(map[string]*Action)}
+}
+
+// Register adds a, replacing any earlier action with the same ID
+func (r *Registry) Register(a Action) {
+	if old, ok := r.byID[a.ID]; ok {
+		*old = a
+	} else {
+		r.actions = append(r.actions, &a)
+		r.byID[a.ID] = &a
+	}
+	r.bind()
+}
+
+// Get returns the action with the given ID
+func (r *Registry) Get(id string) (*Action, bool) {
+	a, ok := r.byID[id]
+	return a, ok
+}
+
+// All returns every action in registration order
+func (r *Registry) All() []*Action {
+	return r.actions
+}
+
+// Run runs the action with the given ID. Returns false if there is none
+// or it cannot run right now.
+func (r *Registry) Run(id string) bool {
+	a, ok := r.byID[id]
+	if !ok || a.Run == nil || !a.Enabled() {
+		return false
+	}
+	a.Run()
+	return true
+}
+
+// KeysFor returns the bindings that currently run the action
+func (r *Registry) KeysFor(id string) []Binding {
+	var keys []Binding
+	for _, b := range r.bindings {
+		if b.action.ID == id {
+			keys = append(keys, b.keys)
+		}
+	}
+	return keys
+}
+
+// Pending returns the chords typed so far of an unfinished sequence
+func (r *Registry) Pending() Binding {
+	return r.pending
+}
+
+// HandleKeys runs the actions whose bindings were pressed since the last
+// frame. Call it once per frame with the context that gets global keys.
+func (r *Registry) HandleKeys(gtx layout.Context) {
+	if len(r.pending) > 0 && time.Since(r.pendingAt) > sequenceTimeout {
+		r.pending = nil
+	}
+	for {
+		// The filters follow the sequence typed so far, so ask again
+		// after every chord
+		ev, ok := gtx.Event(r.filters()...)
+		if !ok {
+			break
+		}
+		if e, ok := ev.(key.Event); ok && e.State == key.Press {
+			r.press(Chord{Mods: e.Modifiers, Name: e.Name})
+		}
+	}
+}
+
+// filters asks for the first chord of every enabled binding and, within
+// a sequence, for the chords that can continue it
+func (r *Registry) filters() []event.Filter {
+	var chords []Chord
+	for _, b := range r.bindings {
+		if !b.action.Enabled() {
+			continue
+		}
+		chords = append(chords, b.keys[0])
+		if len(r.pending) > 0 && len(b.keys) > len(r.pending) && b.keys.hasPrefix(r.pending) {
+			chords = append(chords, b.keys[len(r.pending)])
+		}
+	}
+	filters := make([]event.Filter, 0, len(chords))
+	seen := make(map[Chord]bool)
+	for _, c := range chords {
+		if !seen[c] {
+			seen[c] = true
+			filters = append(filters, key.Filter{Name: c.Name, Required: c.Mods})
+		}
+	}
+	return filters
+}
+
+// press feeds one chord to the bindings
+func (r *Registry) press(c Chord) {
+	seq := append(slices.Clone(r.pending), c)
+	partial := false
+	for _, b := range r.bindings {
+		if !b.action.Enabled() {
+			continue
+		}
+		switch {
+		case slices.Equal(b.keys, seq):
+			r.pending = nil
+			if b.action.Run != nil {
+				b.action.Run()
+			}
+			return
+		case b.keys.hasPrefix(seq):
+			partial = true
+		}
+	}
+	if partial {
+		r.pending, r.pendingAt = seq, time.Now()
+		return
+	}
+	r.pending = nil
+	if len(seq) > 1 {
+		// Not a continuation; maybe it starts something on its own
+		r.press(c)
+	}
+}
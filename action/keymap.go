@@ -0,0 +1,150 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// KeymapEntry maps a key binding, like "Ctrl+K Ctrl+S", to an action ID.
+// An empty Action unbinds the keys.
+type KeymapEntry struct {
+	Keys   string
+	Action string
+}
+
+// LoadKeymap reads a keymap file: a JSON object from bindings to action
+// IDs, in the order they are written, e.g.
+//
+//	{
+//	  "Ctrl+Ö": "editor.toggleEdit",
+//	  "Ctrl+K Ctrl+S": "file.save",
+//	  "Ctrl+T": ""
+//	}
+//
+// A missing file is an empty keymap.
+func LoadKeymap(path string) ([]KeymapEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseKeymap(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// parseKeymap walks the tokens of the object rather than decoding it into
+// a map, so order and repeated keys survive for conflict reports
+func parseKeymap(data []byte) ([]KeymapEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, errors.New("keymap must be a JSON object")
+	}
+	var entries []KeymapEntry
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys, _ := tok.(string)
+		var id *string
+		if err := dec.Decode(&id); err != nil {
+			return nil, fmt.Errorf("%q: action must be a string or null", keys)
+		}
+		entry := KeymapEntry{Keys: keys}
+		if id != nil {
+			entry.Action = *id
+		}
+		entries = append(entries, entry)
+	}
+	if _, err := dec.Token(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SetKeymap applies a keymap over the default bindings and returns what
+// is wrong with the result: bad entries, keys taken from another action
+// and sequences hidden behind shorter ones. Binding an action in the
+// keymap replaces its defaults.
+func (r *Registry) SetKeymap(entries []KeymapEntry) []string {
+	r.keymap = entries
+	return r.bind()
+}
+
+// bind rebuilds the effective bindings: keymap entries first, so they win,
+// then the defaults of actions the keymap leaves alone
+func (r *Registry) bind() []string {
+	var problems []string
+	var bindings []bound
+	var unbound []Binding
+	remapped := make(map[string]bool)
+	for _, e := range r.keymap {
+		keys, err := ParseBinding(e.Keys)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if e.Action == "" {
+			unbound = append(unbound, keys)
+			continue
+		}
+		a, ok := r.byID[e.Action]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown action %q", keys, e.Action))
+			continue
+		}
+		remapped[a.ID] = true
+		bindings = append(bindings, bound{keys, a})
+	}
+	user := len(bindings)
+
+	for _, a := range r.actions {
+		if remapped[a.ID] {
+			continue
+		}
+	defaults:
+		for _, keys := range a.Keys {
+			if slices.ContainsFunc(unbound, func(u Binding) bool { return slices.Equal(u, keys) }) {
+				continue
+			}
+			for _, b := range bindings[:user] {
+				if slices.Equal(b.keys, keys) {
+					problems = append(problems, fmt.Sprintf("%s: runs %q instead of %q", keys, b.action.Title, a.Title))
+					continue defaults
+				}
+			}
+			bindings = append(bindings, bound{keys, a})
+		}
+	}
+
+	for i, x := range bindings {
+		for _, y := range bindings[i+1:] {
+			switch {
+			case x.action == y.action:
+				// Two ways to run the same thing
+			case slices.Equal(x.keys, y.keys):
+				problems = append(problems, fmt.Sprintf("%s: bound to both %q and %q", x.keys, x.action.Title, y.action.Title))
+			case y.keys.hasPrefix(x.keys):
+				problems = append(problems, fmt.Sprintf("%s: hides %s (%s)", x.keys, y.keys, y.action.Title))
+			case x.keys.hasPrefix(y.keys):
+				problems = append(problems, fmt.Sprintf("%s: hides %s (%s)", y.keys, x.keys, x.action.Title))
+			}
+		}
+	}
+
+	r.bindings = bindings
+	r.pending = nil
+	return problems
+}
@@ -0,0 +1,92 @@
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gioui.org/io/key"
+)
+
+func TestParseKeymap(t *testing.T) {
+	entries, err := parseKeymap([]byte(`{
+		"Ctrl+K Ctrl+S": "file.save",
+		"Ctrl+T": null,
+		"Ctrl+T": "tab.new"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KeymapEntry{{"Ctrl+K Ctrl+S", "file.save"}, {"Ctrl+T", ""}, {"Ctrl+T", "tab.new"}}
+	if !slices.Equal(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+
+	for _, bad := range []string{`[]`, `{"Ctrl+S": 1}`, `{"Ctrl+S": "file.save"`, ``} {
+		if _, err := parseKeymap([]byte(bad)); err == nil {
+			t.Errorf("parseKeymap(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLoadKeymap(t *testing.T) {
+	dir := t.TempDir()
+	entries, err := LoadKeymap(filepath.Join(dir, "missing.json"))
+	if err != nil || entries != nil {
+		t.Errorf("missing keymap = %v, %v", entries, err)
+	}
+	p := filepath.Join(dir, "keymap.json")
+	if err := os.WriteFile(p, []byte(`{"Ctrl+S": 2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeymap(p); err == nil || !strings.Contains(err.Error(), p) {
+		t.Errorf("bad keymap error = %v", err)
+	}
+}
+
+func TestParseBinding(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Binding
+	}{
+		{"Ctrl+S", Binding{{key.ModCtrl, "S"}}},
+		{"ctrl+shift+p", Binding{{key.ModCtrl | key.ModShift, "P"}}},
+		{"Ctrl+K Ctrl+S", Binding{{key.ModCtrl, "K"}, {key.ModCtrl, "S"}}},
+		{"Ctrl++", Binding{{key.ModCtrl, "+"}}},
+		{"Alt+Left", Binding{{key.ModAlt, key.NameLeftArrow}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseBinding(tt.spec)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("ParseBinding(%q) = %v, %v, want %v", tt.spec, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "Hyper+S", "Ctrl+Nope", "Ctrl+"} {
+		if _, err := ParseBinding(bad); err == nil {
+			t.Errorf("ParseBinding(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSetKeymap(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Action{ID: "file.save", Title: "Save", Keys: Keys("Ctrl+S")})
+	r.Register(Action{ID: "tab.new", Title: "New Tab", Keys: Keys("Ctrl+T")})
+	r.Register(Action{ID: "tab.close", Title: "Close Tab", Keys: Keys("Ctrl+W")})
+	problems := r.SetKeymap([]KeymapEntry{
+		{"Ctrl+T", "file.save"},
+		{"Ctrl+Q", "no.such"},
+		{"Ctrl+W Ctrl+N", "file.save"},
+	})
+	want := []string{`Ctrl+Q: unknown action "no.such"`, `Ctrl+T: runs "Save" instead of "New Tab"`, `Ctrl+W: hides Ctrl+W Ctrl+N (Save)`}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %q", problems)
+	}
+	for i, w := range want {
+		if !strings.Contains(problems[i], w) {
+			t.Errorf("problem %d = %q, want it to mention %q", i, problems[i], w)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package action
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/io/key"
+)
+
+// Chord is one key press with its modifiers, like Ctrl+Shift+P
+type Chord struct {
+	Mods key.Modifiers
+	Name key.Name
+}
+
+// Binding is a sequence of chords that runs an action, like "Ctrl+K S".
+// Most bindings are a single chord.
+type Binding []Chord
+
+// modNames lists the modifiers in the order they are written
+var modNames = []struct {
+	mod  key.Modifiers
+	name key.Name
+}{
+	{key.ModCtrl, key.NameCtrl},
+	{key.ModCommand, key.NameCommand},
+	{key.ModAlt, key.NameAlt},
+	{key.ModShift, key.NameShift},
+	{key.ModSuper, key.NameSuper},
+}
+
+// modAliases maps the lower-cased modifier names a keymap may use
+var modAliases = map[string]key.Modifiers{
+	"ctrl":     key.ModCtrl,
+	"control":  key.ModCtrl,
+	"shift":    key.ModShift,
+	"alt":      key.ModAlt,
+	"option":   key.ModAlt,
+	"cmd":      key.ModCommand,
+	"command":  key.ModCommand,
+	"super":    key.ModSuper,
+	"meta":     key.ModSuper,
+	"mod":      key.ModShortcut, // Ctrl, or Cmd on macOS
+	"shortcut": key.ModShortcut,
+}
+
+// keyAliases maps the lower-cased key names a keymap may use to Gio's
+var keyAliases = map[string]key.Name{
+	"left":      key.NameLeftArrow,
+	"right":     key.NameRightArrow,
+	"up":        key.NameUpArrow,
+	"down":      key.NameDownArrow,
+	"enter":     key.NameReturn,
+	"return":    key.NameReturn,
+	"esc":       key.NameEscape,
+	"escape":    key.NameEscape,
+	"space":     key.NameSpace,
+	"tab":       key.NameTab,
+	"backspace": key.NameDeleteBackward,
+	"delete":    key.NameDeleteForward,
+	"del":       key.NameDeleteForward,
+	"home":      key.NameHome,
+	"end":       key.NameEnd,
+	"pageup":    key.NamePageUp,
+	"pagedown":  key.NamePageDown,
+	"f1":        key.NameF1,
+	"f2":        key.NameF2,
+	"f3":        key.NameF3,
+	"f4":        key.NameF4,
+	"f5":        key.NameF5,
+	"f6":        key.NameF6,
+	"f7":        key.NameF7,
+	"f8":        key.NameF8,
+	"f9":        key.NameF9,
+	"f10":       key.NameF10,
+	"f11":       key.NameF11,
+	"f12":       key.NameF12,
+}
+
+// displayNames spells out keys whose Gio names are symbols
+var displayNames = map[key.Name]string{
+	key.NameLeftArrow:      "Left",
+	key.NameRightArrow:     "Right",
+	key.NameUpArrow:        "Up",
+	key.NameDownArrow:      "Down",
+	key.NameReturn:         "Enter",
+	key.NameEscape:         "Esc",
+	key.NameDeleteBackward: "Backspace",
+	key.NameDeleteForward:  "Delete",
+}
+
+// ParseChord parses a chord like "Ctrl+Shift+P". Modifier and key names
+// are case-insensitive; a single character names that key, so layouts
+// with keys like "Ö" can bind them directly.
+func ParseChord(s string) (Chord, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.Split(s, "+")
+	if n := len(parts); n >= 2 && parts[n-1] == "" && parts[n-2] == "" {
+		// "Ctrl++" binds the plus key, which splits into two empty fields
+		parts = append(parts[:n-2], "+")
+	}
+	var c Chord
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			return Chord{}, fmt.Errorf("%q: empty key name", s)
+		}
+		if i < len(parts)-1 {
+			mod, ok := modAliases[strings.ToLower(p)]
+			if !ok {
+				return Chord{}, fmt.Errorf("%q: unknown modifier %q", s, p)
+			}
+			c.Mods |= mod
+			continue
+		}
+		if name, ok := keyAliases[strings.ToLower(p)]; ok {
+			c.Name = name
+		} else if utf8.RuneCountInString(p) == 1 {
+			c.Name = key.Name(strings.ToUpper(p))
+		} else {
+			return Chord{}, fmt.Errorf("%q: unknown key %q", s, p)
+		}
+	}
+	return c, nil
+}
+
+// String formats the chord the way menus show it, e.g. "Ctrl+Shift+P"
+func (c Chord) String() string {
+	var parts []string
+	for _, m := range modNames {
+		if c.Mods.Contain(m.mod) {
+			parts = append(parts, string(m.name))
+		}
+	}
+	name := string(c.Name)
+	if d, ok := displayNames[c.Name]; ok {
+		name = d
+	}
+	return strings.Join(append(parts, name), "+")
+}
+
+// ParseBinding parses chords separated by spaces, like "Ctrl+K Ctrl+S"
+func ParseBinding(s string) (Binding, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty key binding")
+	}
+	b := make(Binding, 0, len(fields))
+	for _, f := range fields {
+		c, err := ParseChord(f)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, c)
+	}
+	return b, nil
+}
+
+// Keys parses the default bindings of an action. It panics on a bad
+// spec, since defaults are written in the source.
+func Keys(specs ...string) []Binding {
+	bs := make([]Binding, len(specs))
+	for i, s := range specs {
+		b, err := ParseBinding(s)
+		if err != nil {
+			panic("action: " + err.Error())
+		}
+		bs[i] = b
+	}
+	return bs
+}
+
+// String formats the binding the way menus show it, e.g. "Ctrl+K S"
+func (b Binding) String() string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// hasPrefix reports whether b starts with the chords of p
+func (b Binding) hasPrefix(p Binding) bool {
+	return len(b) >= len(p) && slices.Equal(b[:len(p)], p)
+}
@@ -0,0 +1,16 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ConfigDir returns the directory giopad keeps its settings in:
+// $XDG_CONFIG_HOME/giopad, or the platform's equivalent
+func ConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "giopad"), nil
+}
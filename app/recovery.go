@@ -0,0 +1,15 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+)
+
+// RecoveryPath returns where the unsaved edits to note are kept when the
+// window closes under them: in the recovery folder of configDir, named by
+// a hash of the note's path so the next open of the note finds them
+func RecoveryPath(configDir, note string) string {
+	sum := sha256.Sum256([]byte(note))
+	return filepath.Join(configDir, "recovery", hex.EncodeToString(sum[:12])+".md")
+}
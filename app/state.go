@@ -1,18 +1,132 @@
 package app
 
-// State holds the application state
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"giopad/fs"
+)
+
+const (
+	maxRecentVaults = 10
+	maxPositions    = 200 // notes per vault whose caret and scroll are kept
+)
+
+// State holds the application state that survives restarts
 type State struct {
-	VaultPath    string   // Root path of the markdown vault
-	SelectedFile string   // Currently selected file path
-	OpenFiles    []string // List of open file paths (tabs)
-	TreeExpanded map[string]bool // Which directories are expanded
-	DrawerOpen   bool     // Mobile: is the file tree drawer open?
+	VaultPath    string                 `json:"vault_path,omitempty"`    // Root path of the markdown vault
+	RecentVaults []string               `json:"recent_vaults,omitempty"` // Most recent first, including VaultPath
+	Theme        string                 `json:"theme,omitempty"`         // "light" or "dark"
+	Vaults       map[string]*VaultState `json:"vaults,omitempty"`        // Per-vault session, by root path
+}
+
+// VaultState is the session of one vault
+type VaultState struct {
+	SelectedFile string              `json:"selected_file,omitempty"` // Currently selected file path
+	OpenFiles    []string            `json:"open_files,omitempty"`    // List of open file paths (tabs)
+	TreeExpanded map[string]bool     `json:"tree_expanded,omitempty"` // Which directories are expanded
+	RecentFiles  []string            `json:"recent_files,omitempty"`  // Most recently opened first
+	Positions    map[string]Position `json:"positions,omitempty"`     // Where each note was left
+}
+
+// Position is where a note was left: the caret as a rune offset, the
+// source scroll offset in pixels and the first source line showing in the
+// rendered view
+type Position struct {
+	Caret  int `json:"caret,omitempty"`
+	Scroll int `json:"scroll,omitempty"`
+	Line   int `json:"line,omitempty"`
 }
 
 // NewState creates a new application state
 func NewState(vaultPath string) *State {
-	return &State{
-		VaultPath:    vaultPath,
-		TreeExpanded: make(map[string]bool),
+	s := &State{Vaults: make(map[string]*VaultState)}
+	if vaultPath != "" {
+		s.UseVault(vaultPath)
+	}
+	return s
+}
+
+// LoadState reads the state saved at path. A missing file gives a new,
+// empty state. A file that is not valid JSON is moved to path + ".bad", so
+// the next Save does not write over what it held.
+func LoadState(path string) (*State, error) {
+	s := NewState("")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		if rerr := os.Rename(path, path+".bad"); rerr != nil {
+			return NewState(""), errors.Join(err, rerr)
+		}
+		return NewState(""), fmt.Errorf("%w (kept as %s.bad)", err, path)
+	}
+	if s.Vaults == nil {
+		s.Vaults = make(map[string]*VaultState)
+	}
+	return s, nil
+}
+
+// Save writes the state to path, replacing the old file atomically
+func (s *State) Save(path string) error {
+	for _, v := range s.Vaults {
+		v.prune()
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fs.WriteFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// UseVault makes path the current vault and moves it to the front of the
+// recent vaults
+func (s *State) UseVault(path string) {
+	s.VaultPath = path
+	s.RecentVaults = slices.DeleteFunc(s.RecentVaults, func(p string) bool { return p == path })
+	s.RecentVaults = slices.Insert(s.RecentVaults, 0, path)
+	if len(s.RecentVaults) > maxRecentVaults {
+		s.RecentVaults = s.RecentVaults[:maxRecentVaults]
+	}
+}
+
+// Vault returns the session of the vault at path, creating an empty one
+func (s *State) Vault(path string) *VaultState {
+	v, ok := s.Vaults[path]
+	if !ok {
+		v = &VaultState{}
+		s.Vaults[path] = v
+	}
+	if v.TreeExpanded == nil {
+		v.TreeExpanded = make(map[string]bool)
+	}
+	if v.Positions == nil {
+		v.Positions = make(map[string]Position)
+	}
+	return v
+}
+
+// prune drops collapsed folders and, past maxPositions, the positions of
+// notes that are neither recent nor open
+func (v *VaultState) prune() {
+	for p, open := range v.TreeExpanded {
+		if !open {
+			delete(v.TreeExpanded, p)
+		}
+	}
+	if len(v.Positions) <= maxPositions {
+		return
+	}
+	for p := range v.Positions {
+		if p != v.SelectedFile && !slices.Contains(v.RecentFiles, p) && !slices.Contains(v.OpenFiles, p) {
+			delete(v.Positions, p)
+		}
 	}
 }
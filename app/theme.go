@@ -80,6 +80,15 @@ func ToggleTheme() {
 	}
 }
 
+// SetDark switches to the dark palette, or to the light one
+func SetDark(dark bool) {
+	if dark {
+		CurrentTheme = AyuMirage
+	} else {
+		CurrentTheme = AyuLight
+	}
+}
+
 // AyuMirageTheme returns a material.Theme configured with current colors
 func AyuMirageTheme() *material.Theme {
 	th := material.NewTheme()
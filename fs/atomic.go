@@ -0,0 +1,58 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic replaces the contents of path without ever leaving a
+// truncated file behind: data goes to a hidden temp file in the same
+// directory, is synced to disk, then renamed over path. The file keeps its
+// permissions; new files get perm.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	// Write through symlinks instead of replacing them
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes a directory entry change to disk, where supported
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,57 @@
+package fs
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ConflictInfo describes a Syncthing conflict copy, named like
+// note.sync-conflict-20240131-154502-ABCDEFG.md
+type ConflictInfo struct {
+	Original string    // Name of the file the copy conflicts with
+	Time     time.Time // When the conflict was detected
+	Device   string    // Short ID of the device that made the losing edit
+}
+
+var conflictExp = regexp.MustCompile(`^(.*)\.sync-conflict-(\d{8}-\d{6})-([A-Z0-9]+)(\.[^.]*)?$`)
+
+// ParseConflictName reports whether name is a Syncthing conflict copy
+func ParseConflictName(name string) (ConflictInfo, bool) {
+	m := conflictExp.FindStringSubmatch(name)
+	if m == nil {
+		return ConflictInfo{}, false
+	}
+	t, err := time.ParseInLocation("20060102-150405", m[2], time.Local)
+	if err != nil {
+		return ConflictInfo{}, false
+	}
+	return ConflictInfo{Original: m[1] + m[4], Time: t, Device: m[3]}, true
+}
+
+// groupConflicts moves conflict copies out of parent.Children and under
+// the file they conflict with. Copies whose original is gone stay put.
+func groupConflicts(parent *Node) {
+	files := make(map[string]*Node)
+	for _, child := range parent.Children {
+		if !child.IsDir {
+			files[strings.ToLower(child.Name)] = child
+		}
+	}
+
+	kept := parent.Children[:0]
+	for _, child := range parent.Children {
+		if !child.IsDir {
+			if info, ok := ParseConflictName(child.Name); ok {
+				if orig := files[strings.ToLower(info.Original)]; orig != nil && orig != child {
+					child.ConflictOf = orig.Path
+					child.Depth = orig.Depth + 1
+					orig.Conflicts = append(orig.Conflicts, child)
+					continue
+				}
+			}
+		}
+		kept = append(kept, child)
+	}
+	parent.Children = kept
+}
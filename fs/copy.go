@@ -0,0 +1,92 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CreateCopy creates an empty file or directory in dir named after name:
+// "note (copy).md", then "note (copy 2).md" and so on until one is free.
+// Returns the new path.
+func CreateCopy(vfs VaultFS, dir, name string, isDir bool) (string, error) {
+	for i := 1; ; i++ {
+		path, err := vfs.Create(dir, numberedName(name, isDir, "copy", i), isDir)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		return path, err
+	}
+}
+
+// WriteCopy creates a free "name (copy).md" in dir, as CreateCopy does,
+// and writes data to it. A copy that cannot be written is deleted again.
+// Returns the new path.
+func WriteCopy(vfs VaultFS, dir, name string, data []byte) (string, error) {
+	path, err := CreateCopy(vfs, dir, name, false)
+	if err != nil {
+		return "", err
+	}
+	if err := vfs.WriteFile(path, data); err != nil {
+		return "", errors.Join(err, vfs.Delete(path))
+	}
+	return path, nil
+}
+
+// numberedName returns "stem (label).ext" for i == 1 and "stem (label i).ext"
+// after that. Directories have no extension.
+func numberedName(name string, isDir bool, label string, i int) string {
+	ext := filepath.Ext(name)
+	if isDir {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(name, ext)
+	if i > 1 {
+		label += " " + strconv.Itoa(i)
+	}
+	return stem + " (" + label + ")" + ext
+}
+
+// Duplicate copies a file, or a directory and everything below it, next to
+// the original inside dir. Returns the path of the copy.
+func Duplicate(vfs VaultFS, path, dir string) (string, error) {
+	info, err := vfs.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	dst, err := CreateCopy(vfs, dir, info.Name, info.IsDir)
+	if err != nil {
+		return "", err
+	}
+	if err := copyInto(vfs, info, dst); err != nil {
+		return "", errors.Join(err, vfs.Delete(dst)) // No half-made copy
+	}
+	return dst, nil
+}
+
+// copyInto fills the already created dst with the contents of src
+func copyInto(vfs VaultFS, src Entry, dst string) error {
+	if !src.IsDir {
+		data, err := vfs.ReadFile(src.Path)
+		if err != nil {
+			return err
+		}
+		return vfs.WriteFile(dst, data)
+	}
+	entries, err := vfs.List(src.Path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		child, err := vfs.Create(dst, entry.Name, entry.IsDir)
+		if err != nil {
+			return err
+		}
+		if err := copyInto(vfs, entry, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package fs
+
+import (
+	"errors"
+	"testing"
+)
+
+// failingWrites is a MemFS whose WriteFile always fails
+type failingWrites struct {
+	*MemFS
+}
+
+func (failingWrites) WriteFile(path string, data []byte) error {
+	return errors.New("disk full")
+}
+
+func TestWriteCopy(t *testing.T) {
+	m := newTestVault(t)
+	for _, want := range []string{"/vault/notes/a (copy).md", "/vault/notes/a (copy 2).md"} {
+		path, err := WriteCopy(m, "/vault/notes", "a.md", []byte("b"))
+		if err != nil || path != want {
+			t.Fatalf("WriteCopy = %q, %v, want %q", path, err, want)
+		}
+		if data, _ := m.ReadFile(path); string(data) != "b" {
+			t.Errorf("%s holds %q", path, data)
+		}
+	}
+
+	// A copy that cannot be written does not stay behind empty
+	path, err := WriteCopy(failingWrites{m}, "/vault/notes", "a.md", []byte("b"))
+	if err == nil || path != "" {
+		t.Fatalf("WriteCopy = %q, %v, want an error", path, err)
+	}
+	if _, err := m.Stat("/vault/notes/a (copy 3).md"); err == nil {
+		t.Error("the copy that failed to write is still there")
+	}
+}
@@ -0,0 +1,255 @@
+package fs
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Loader lists directories for the tree in the background, so neither a
+// large vault nor a slow SAF provider stalls the UI. Load starts a listing;
+// Apply, called on the UI goroutine, hands finished listings to their nodes.
+type Loader struct {
+	vfs        VaultFS
+	invalidate func()
+
+	// MarkdownOnly hides folders with no markdown anywhere below them.
+	// Empty folders still show, and so do the folders holding them, so
+	// freshly created ones do not vanish.
+	MarkdownOnly bool
+
+	mu      sync.Mutex
+	done    []loadResult
+	visible map[string]bool // cached per-directory MarkdownOnly answers
+}
+
+// loadResult is a finished listing waiting for Apply
+type loadResult struct {
+	node     *Node
+	children []*Node
+	err      error
+}
+
+// NewLoader creates a loader for vfs. invalidate is called from the
+// background whenever a listing is ready to Apply.
+func NewLoader(vfs VaultFS, invalidate func()) *Loader {
+	return &Loader{
+		vfs:        vfs,
+		invalidate: invalidate,
+		visible:    make(map[string]bool),
+	}
+}
+
+// Load lists the children of the directory node n in the background,
+// unless that is already underway
+func (l *Loader) Load(n *Node) {
+	if n == nil || !n.IsDir || n.Loading {
+		return
+	}
+	n.Loading = true
+	path, depth, markdownOnly := n.Path, n.Depth, l.MarkdownOnly
+	go func() {
+		children, err := l.list(path, depth, markdownOnly)
+		l.mu.Lock()
+		l.done = append(l.done, loadResult{node: n, children: children, err: err})
+		l.mu.Unlock()
+		if l.invalidate != nil {
+			l.invalidate()
+		}
+	}()
+}
+
+// Reload lists n again. If a listing is already underway, another one
+// follows it so changes made meanwhile are not missed.
+func (l *Loader) Reload(n *Node) {
+	if n == nil {
+		return
+	}
+	if n.Loading {
+		n.reload = true
+		return
+	}
+	l.Load(n)
+}
+
+// Apply puts finished listings into the tree. Subdirectories that were
+// already loaded keep their subtree. Returns true if anything changed.
+func (l *Loader) Apply() bool {
+	l.mu.Lock()
+	done := l.done
+	l.done = nil
+	l.mu.Unlock()
+
+	for _, r := range done {
+		n := r.node
+		n.Loading = false
+		n.Loaded = true
+		n.Err = r.err
+		if r.err == nil {
+			n.Children = keepLoaded(n.Children, r.children)
+		}
+		if n.reload {
+			n.reload = false
+			l.Load(n)
+		}
+	}
+	return len(done) > 0
+}
+
+// keepLoaded swaps the directories in fresh for their nodes in old, so
+// loaded subtrees and listings underway survive a rescan of their parent
+func keepLoaded(old, fresh []*Node) []*Node {
+	dirs := make(map[string]*Node)
+	for _, n := range old {
+		if n.IsDir {
+			dirs[n.Path] = n
+		}
+	}
+	for i, n := range fresh {
+		if prev := dirs[n.Path]; prev != nil && n.IsDir {
+			prev.Name = n.Name
+			fresh[i] = prev
+		}
+	}
+	return fresh
+}
+
+// Rescan forgets what is cached about dir and lists it again. Use it after
+// changing the vault through VaultFS; it works on SAF trees too.
+func (l *Loader) Rescan(root *Node, dir string) {
+	l.Invalidate(root, dir)
+	if n := FindNode(root, dir); n != nil && n.IsDir {
+		l.Reload(n)
+	}
+}
+
+// Patch applies a watcher event by listing again the nearest loaded
+// directory that contains changed. Nodes elsewhere are left untouched.
+// Paths are OS paths, as reported by Watcher.
+func (l *Loader) Patch(root *Node, changed string) {
+	if root == nil {
+		return
+	}
+	dir := filepath.Dir(changed)
+	if changed == root.Path {
+		dir = root.Path
+	}
+	l.Invalidate(root, dir)
+	for {
+		if n := FindNode(root, dir); n != nil && n.IsDir && n.Loaded {
+			l.Reload(n)
+			return
+		}
+		if dir == root.Path || !strings.HasPrefix(dir, root.Path) {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Invalidate drops the cached MarkdownOnly answers for dir and every folder
+// above it, since a change in dir can flip them all
+func (l *Loader) Invalidate(root *Node, dir string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !IsSAFURI(dir) {
+		// Subfolders may have been moved or deleted along with dir
+		prefix := dir + string(filepath.Separator)
+		for p := range l.visible {
+			if strings.HasPrefix(p, prefix) {
+				delete(l.visible, p)
+			}
+		}
+	}
+	for p := dir; p != ""; {
+		delete(l.visible, p)
+		parent := ParentPath(root, p)
+		if p == root.Path || parent == p {
+			break
+		}
+		p = parent
+	}
+}
+
+// list reads one level of path: directories and markdown files, sorted,
+// with conflict copies grouped under their originals. Runs in the
+// background, so it builds fresh nodes and never touches the tree.
+func (l *Loader) list(path string, depth int, markdownOnly bool) ([]*Node, error) {
+	entries, err := l.vfs.List(path)
+	if err != nil {
+		return nil, err
+	}
+	sortEntries(entries)
+
+	parent := &Node{Path: path, IsDir: true, Depth: depth}
+	for _, entry := range entries {
+		// Skip hidden files/dirs
+		if strings.HasPrefix(entry.Name, ".") {
+			continue
+		}
+		if entry.IsDir {
+			if markdownOnly {
+				if visible, ok := l.summarize(entry.Path); !ok || !visible {
+					continue
+				}
+			}
+		} else if !IsMarkdown(entry.Name) {
+			continue
+		}
+		parent.Children = append(parent.Children, &Node{
+			Path:  entry.Path,
+			Name:  entry.Name,
+			IsDir: entry.IsDir,
+			Depth: depth + 1,
+		})
+	}
+
+	groupConflicts(parent)
+	return parent.Children, nil
+}
+
+// summarize answers whether dir shows with MarkdownOnly: it is empty, or
+// holds markdown or an empty folder somewhere below it. It walks only as
+// far as needed and caches every answer it works out. ok is false if dir
+// cannot be read.
+func (l *Loader) summarize(dir string) (visible, ok bool) {
+	l.mu.Lock()
+	visible, ok = l.visible[dir]
+	l.mu.Unlock()
+	if ok {
+		return visible, true
+	}
+
+	entries, err := l.vfs.List(dir)
+	if err != nil {
+		return false, false
+	}
+	empty := true
+	var subdirs []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name, ".") {
+			continue
+		}
+		empty = false
+		if entry.IsDir {
+			subdirs = append(subdirs, entry.Path)
+		} else if IsMarkdown(entry.Name) {
+			visible = true
+		}
+	}
+	visible = visible || empty
+	// Files first, so a note at this level spares the walk below
+	for _, sub := range subdirs {
+		if visible {
+			break
+		}
+		if v, ok := l.summarize(sub); ok && v {
+			visible = true
+		}
+	}
+
+	l.mu.Lock()
+	l.visible[dir] = visible
+	l.mu.Unlock()
+	return visible, true
+}
@@ -0,0 +1,100 @@
+package fs
+
+import (
+	"testing"
+	"time"
+)
+
+// applied waits for l to call invalidate and applies what it finished
+func applied(t *testing.T, l *Loader, ready chan struct{}) {
+	t.Helper()
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listing never finished")
+	}
+	if !l.Apply() {
+		t.Fatal("Apply found nothing to do")
+	}
+}
+
+func names(nodes []*Node) []string {
+	var s []string
+	for _, n := range nodes {
+		s = append(s, n.Name)
+	}
+	return s
+}
+
+func TestLoaderLoad(t *testing.T) {
+	m := newTestVault(t)
+	for _, p := range []string{"/vault/b.md", "/vault/image.png", "/vault/.hidden.md"} {
+		if err := m.WriteFile(p, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.MkdirAll("/vault/pictures"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteFile("/vault/pictures/c.png", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ready := make(chan struct{}, 1)
+	l := NewLoader(m, func() { ready <- struct{}{} })
+	root := &Node{Path: "/vault", IsDir: true}
+	l.Load(root)
+	if !root.Loading {
+		t.Error("root is not marked loading")
+	}
+	applied(t, l, ready)
+	if !root.Loaded || root.Loading || root.Err != nil {
+		t.Fatalf("root loaded=%v loading=%v err=%v", root.Loaded, root.Loading, root.Err)
+	}
+	if got := names(root.Children); len(got) != 3 || got[0] != "notes" || got[1] != "pictures" || got[2] != "b.md" {
+		t.Errorf("children = %q", got)
+	}
+
+	// Without markdown below it, a folder is hidden
+	l.MarkdownOnly = true
+	l.Reload(root)
+	applied(t, l, ready)
+	if got := names(root.Children); len(got) != 2 || got[0] != "notes" {
+		t.Errorf("markdown-only children = %q", got)
+	}
+
+	// An empty folder shows, and so does the folder holding it
+	if err := m.MkdirAll("/vault/pictures/new"); err != nil {
+		t.Fatal(err)
+	}
+	l.Invalidate(root, "/vault/pictures")
+	l.Reload(root)
+	applied(t, l, ready)
+	if got := names(root.Children); len(got) != 3 || got[1] != "pictures" {
+		t.Errorf("children with an empty subfolder = %q", got)
+	}
+}
+
+func TestLoaderKeepsLoaded(t *testing.T) {
+	m := newTestVault(t)
+	ready := make(chan struct{}, 1)
+	l := NewLoader(m, func() { ready <- struct{}{} })
+	root := &Node{Path: "/vault", IsDir: true}
+	l.Load(root)
+	applied(t, l, ready)
+	notes := root.Children[0]
+	l.Load(notes)
+	applied(t, l, ready)
+
+	if err := m.WriteFile("/vault/z.md", nil); err != nil {
+		t.Fatal(err)
+	}
+	l.Reload(root)
+	applied(t, l, ready)
+	if root.Children[0] != notes || !notes.Loaded || len(notes.Children) != 1 {
+		t.Error("reloading the root dropped the loaded subfolder")
+	}
+	if got := names(root.Children); len(got) != 2 || got[1] != "z.md" {
+		t.Errorf("children = %q", got)
+	}
+}
@@ -0,0 +1,212 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an in-memory VaultFS with slash-separated paths. It lets vault
+// logic run without Android or a real directory.
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string]*memFile
+	now   func() time.Time
+}
+
+var errIsDir = errors.New("is a directory")
+
+type memFile struct {
+	data    []byte
+	isDir   bool
+	modTime time.Time
+}
+
+// NewMemFS creates an empty MemFS holding only the root directory "/"
+func NewMemFS() *MemFS {
+	m := &MemFS{
+		files: make(map[string]*memFile),
+		now:   time.Now,
+	}
+	m.files["/"] = &memFile{isDir: true, modTime: m.now()}
+	return m
+}
+
+// MkdirAll creates a directory and any missing parents
+func (m *MemFS) MkdirAll(dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mkdirAll(path.Clean("/" + dir))
+}
+
+func (m *MemFS) mkdirAll(dir string) error {
+	if f, ok := m.files[dir]; ok {
+		if !f.isDir {
+			return &os.PathError{Op: "mkdir", Path: dir, Err: os.ErrExist}
+		}
+		return nil
+	}
+	if err := m.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+	m.files[dir] = &memFile{isDir: true, modTime: m.now()}
+	return nil
+}
+
+// List returns the direct children of dir
+func (m *MemFS) List(dir string) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir = path.Clean(dir)
+	if f, ok := m.files[dir]; !ok || !f.isDir {
+		return nil, &os.PathError{Op: "readdir", Path: dir, Err: os.ErrNotExist}
+	}
+	var entries []Entry
+	for p, f := range m.files {
+		if p == dir || path.Dir(p) != dir {
+			continue
+		}
+		entries = append(entries, m.entry(p, f))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// Stat returns metadata for a single file or directory
+func (m *MemFS) Stat(p string) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p = path.Clean(p)
+	f, ok := m.files[p]
+	if !ok {
+		return Entry{}, &os.PathError{Op: "stat", Path: p, Err: os.ErrNotExist}
+	}
+	return m.entry(p, f), nil
+}
+
+// ReadFile returns a copy of a file's contents
+func (m *MemFS) ReadFile(p string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p = path.Clean(p)
+	f, ok := m.files[p]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+	}
+	if f.isDir {
+		return nil, &os.PathError{Op: "read", Path: p, Err: errIsDir}
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+// WriteFile replaces a file's contents, creating it if its parent exists
+func (m *MemFS) WriteFile(p string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p = path.Clean(p)
+	if parent, ok := m.files[path.Dir(p)]; !ok || !parent.isDir {
+		return &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+	}
+	if f, ok := m.files[p]; ok && f.isDir {
+		return &os.PathError{Op: "open", Path: p, Err: errIsDir}
+	}
+	m.files[p] = &memFile{data: append([]byte(nil), data...), modTime: m.now()}
+	return nil
+}
+
+// Create makes an empty file or directory, failing if it already exists
+func (m *MemFS) Create(dir, name string, isDir bool) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir = path.Clean(dir)
+	p := path.Join(dir, name)
+	if parent, ok := m.files[dir]; !ok || !parent.isDir {
+		return "", &os.PathError{Op: "create", Path: p, Err: os.ErrNotExist}
+	}
+	if _, ok := m.files[p]; ok {
+		return "", &os.PathError{Op: "create", Path: p, Err: os.ErrExist}
+	}
+	m.files[p] = &memFile{isDir: isDir, modTime: m.now()}
+	return p, nil
+}
+
+// Rename gives path a new base name, moving any children along with it
+func (m *MemFS) Rename(p, newName string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p = path.Clean(p)
+	return m.move(p, path.Join(path.Dir(p), newName), "rename")
+}
+
+// Move puts path into toDir, moving any children along with it
+func (m *MemFS) Move(p, toDir string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p = path.Clean(p)
+	toDir = path.Clean(toDir)
+	if dir, ok := m.files[toDir]; !ok || !dir.isDir {
+		return "", &os.LinkError{Op: "move", Old: p, New: toDir, Err: os.ErrNotExist}
+	}
+	if toDir == p || strings.HasPrefix(toDir, p+"/") {
+		return "", &os.LinkError{Op: "move", Old: p, New: toDir, Err: os.ErrInvalid}
+	}
+	return m.move(p, path.Join(toDir, path.Base(p)), "move")
+}
+
+func (m *MemFS) move(p, newPath, op string) (string, error) {
+	if _, ok := m.files[p]; !ok {
+		return "", &os.LinkError{Op: op, Old: p, New: newPath, Err: os.ErrNotExist}
+	}
+	if _, ok := m.files[newPath]; ok {
+		return "", &os.LinkError{Op: op, Old: p, New: newPath, Err: os.ErrExist}
+	}
+	moved := make(map[string]*memFile)
+	for old, f := range m.files {
+		if old == p || strings.HasPrefix(old, p+"/") {
+			moved[newPath+strings.TrimPrefix(old, p)] = f
+			delete(m.files, old)
+		}
+	}
+	for np, f := range moved {
+		m.files[np] = f
+	}
+	return newPath, nil
+}
+
+// Delete removes a file or a whole directory
+func (m *MemFS) Delete(p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p = path.Clean(p)
+	if _, ok := m.files[p]; !ok {
+		return &os.PathError{Op: "remove", Path: p, Err: os.ErrNotExist}
+	}
+	for old := range m.files {
+		if old == p || strings.HasPrefix(old, p+"/") {
+			delete(m.files, old)
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) entry(p string, f *memFile) Entry {
+	return Entry{
+		Name:    path.Base(p),
+		Path:    p,
+		IsDir:   f.isDir,
+		Size:    int64(len(f.data)),
+		ModTime: f.modTime,
+	}
+}
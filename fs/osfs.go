@@ -0,0 +1,107 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// OSFS is a VaultFS over the local filesystem
+type OSFS struct{}
+
+// List returns the direct children of dir
+func (OSFS) List(dir string) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		entry := Entry{
+			Name:  de.Name(),
+			Path:  filepath.Join(dir, de.Name()),
+			IsDir: de.IsDir(),
+		}
+		if info, err := de.Info(); err == nil {
+			entry.Size = info.Size()
+			entry.ModTime = info.ModTime()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Stat returns metadata for a single file or directory
+func (OSFS) Stat(path string) (Entry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{
+		Name:    info.Name(),
+		Path:    path,
+		IsDir:   info.IsDir(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+// ReadFile returns the contents of a file
+func (OSFS) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// WriteFile replaces the contents of a file atomically
+func (OSFS) WriteFile(path string, data []byte) error {
+	return WriteFileAtomic(path, data, 0644)
+}
+
+// Create makes an empty file or directory, failing if it already exists
+func (OSFS) Create(dir, name string, isDir bool) (string, error) {
+	path := filepath.Join(dir, name)
+	if isDir {
+		if err := os.Mkdir(path, 0755); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Rename gives path a new base name within the same directory
+func (OSFS) Rename(path, newName string) (string, error) {
+	newPath := filepath.Join(filepath.Dir(path), newName)
+	if _, err := os.Lstat(newPath); err == nil {
+		return "", &os.LinkError{Op: "rename", Old: path, New: newPath, Err: os.ErrExist}
+	}
+	if err := os.Rename(path, newPath); err != nil {
+		return "", err
+	}
+	return newPath, nil
+}
+
+// Move puts path into toDir, keeping its name
+func (OSFS) Move(path, toDir string) (string, error) {
+	newPath := filepath.Join(toDir, filepath.Base(path))
+	if _, err := os.Lstat(newPath); err == nil {
+		return "", &os.LinkError{Op: "move", Old: path, New: newPath, Err: os.ErrExist}
+	}
+	if err := os.Rename(path, newPath); err != nil {
+		return "", err
+	}
+	return newPath, nil
+}
+
+// Delete removes a file or a whole directory
+func (OSFS) Delete(path string) error {
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
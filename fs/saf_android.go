@@ -13,7 +13,9 @@ package fs
 import "C"
 import (
 	"errors"
+	"strconv"
 	"strings"
+	"time"
 
 	"gioui.org/app"
 	"git.wow.st/gmp/jni"
@@ -26,6 +28,11 @@ var (
 	readFileID    jni.MethodID
 	writeFileID   jni.MethodID
 	getTreeNameID jni.MethodID
+	statDocID     jni.MethodID
+	createDocID   jni.MethodID
+	renameDocID   jni.MethodID
+	deleteDocID   jni.MethodID
+	moveDocID     jni.MethodID
 	initialized   bool
 )
 
@@ -45,6 +52,11 @@ func initSAF(env jni.Env) error {
 	readFileID = jni.GetStaticMethodID(env, safClass, "readFile", "(Landroid/content/Context;Ljava/lang/String;)[B")
 	writeFileID = jni.GetStaticMethodID(env, safClass, "writeFile", "(Landroid/content/Context;Ljava/lang/String;[B)Z")
 	getTreeNameID = jni.GetStaticMethodID(env, safClass, "getTreeName", "(Landroid/content/Context;Ljava/lang/String;)Ljava/lang/String;")
+	statDocID = jni.GetStaticMethodID(env, safClass, "statDoc", "(Landroid/content/Context;Ljava/lang/String;Ljava/lang/String;)Ljava/lang/String;")
+	createDocID = jni.GetStaticMethodID(env, safClass, "createDoc", "(Landroid/content/Context;Ljava/lang/String;Ljava/lang/String;Ljava/lang/String;Ljava/lang/String;)Ljava/lang/String;")
+	renameDocID = jni.GetStaticMethodID(env, safClass, "renameDoc", "(Landroid/content/Context;Ljava/lang/String;Ljava/lang/String;)Ljava/lang/String;")
+	deleteDocID = jni.GetStaticMethodID(env, safClass, "deleteDoc", "(Landroid/content/Context;Ljava/lang/String;)Z")
+	moveDocID = jni.GetStaticMethodID(env, safClass, "moveDoc", "(Landroid/content/Context;Ljava/lang/String;Ljava/lang/String;Ljava/lang/String;)Ljava/lang/String;")
 
 	initialized = true
 	return nil
@@ -126,7 +138,7 @@ func ReadSAFFile(docURI string) ([]byte, error) {
 		}
 
 		if result == 0 {
-			return nil
+			return errors.New("failed to read file")
 		}
 
 		content = jni.GetByteArrayElements(env, jni.ByteArray(result))
@@ -169,15 +181,17 @@ func parseEntries(data string) []SAFEntry {
 	entries := make([]SAFEntry, 0, len(lines))
 
 	for _, line := range lines {
-		parts := strings.SplitN(line, "|", 3)
-		if len(parts) != 3 {
+		// type|name|uri: URIs escape "|", so a name may hold one
+		kind, rest, ok := strings.Cut(line, "|")
+		i := strings.LastIndexByte(rest, '|')
+		if !ok || i < 0 {
 			continue
 		}
 
 		entries = append(entries, SAFEntry{
-			IsDir: parts[0] == "d",
-			Name:  parts[1],
-			URI:   parts[2],
+			IsDir: kind == "d",
+			Name:  rest[:i],
+			URI:   rest[i+1:],
 		})
 	}
 
@@ -214,7 +228,177 @@ func WriteSAFFile(docURI string, data []byte) error {
 	return nil
 }
 
-// IsSAFURI checks if a path is a SAF content URI
-func IsSAFURI(path string) bool {
-	return strings.HasPrefix(path, "content://")
+// SAFStat describes a single SAF document
+type SAFStat struct {
+	SAFEntry
+	Size    int64
+	ModTime time.Time
+}
+
+// StatSAFDoc returns metadata for a document inside a SAF tree
+func StatSAFDoc(treeURI, docURI string) (SAFStat, error) {
+	var st SAFStat
+
+	err := jni.Do(jni.JVMFor(app.JavaVM()), func(env jni.Env) error {
+		if err := initSAF(env); err != nil {
+			return err
+		}
+
+		ctx := jni.Object(app.AppContext())
+		treeStr := jni.JavaString(env, treeURI)
+		docStr := jni.JavaString(env, docURI)
+
+		result, err := jni.CallStaticObjectMethod(env, safClass, statDocID, jni.Value(ctx), jni.Value(treeStr), jni.Value(docStr))
+		if err != nil {
+			return err
+		}
+
+		resultStr := jni.GoString(env, jni.String(result))
+		if err := safError(resultStr); err != nil {
+			return err
+		}
+		parts := strings.SplitN(resultStr, "|", 4)
+		if len(parts) != 4 {
+			return errors.New("malformed stat result")
+		}
+		// type|size|mtime|name: the name is last so a "|" in it stays whole
+		size, _ := strconv.ParseInt(parts[1], 10, 64)
+		millis, _ := strconv.ParseInt(parts[2], 10, 64)
+		st = SAFStat{
+			SAFEntry: SAFEntry{IsDir: parts[0] == "d", Name: parts[3], URI: docURI},
+			Size:     size,
+			ModTime:  time.UnixMilli(millis),
+		}
+		return nil
+	})
+
+	return st, err
+}
+
+// CreateSAFDoc creates a document of the given MIME type under parentURI
+// and returns its URI
+func CreateSAFDoc(treeURI, parentURI, mimeType, name string) (string, error) {
+	var uri string
+
+	err := jni.Do(jni.JVMFor(app.JavaVM()), func(env jni.Env) error {
+		if err := initSAF(env); err != nil {
+			return err
+		}
+
+		ctx := jni.Object(app.AppContext())
+		treeStr := jni.JavaString(env, treeURI)
+		parentStr := jni.JavaString(env, parentURI)
+		mimeStr := jni.JavaString(env, mimeType)
+		nameStr := jni.JavaString(env, name)
+
+		result, err := jni.CallStaticObjectMethod(env, safClass, createDocID, jni.Value(ctx), jni.Value(treeStr), jni.Value(parentStr), jni.Value(mimeStr), jni.Value(nameStr))
+		if err != nil {
+			return err
+		}
+
+		resultStr := jni.GoString(env, jni.String(result))
+		if err := safError(resultStr); err != nil {
+			return err
+		}
+		uri = resultStr
+		return nil
+	})
+
+	return uri, err
+}
+
+// RenameSAFDoc renames a document and returns its new URI
+func RenameSAFDoc(docURI, name string) (string, error) {
+	var uri string
+
+	err := jni.Do(jni.JVMFor(app.JavaVM()), func(env jni.Env) error {
+		if err := initSAF(env); err != nil {
+			return err
+		}
+
+		ctx := jni.Object(app.AppContext())
+		uriStr := jni.JavaString(env, docURI)
+		nameStr := jni.JavaString(env, name)
+
+		result, err := jni.CallStaticObjectMethod(env, safClass, renameDocID, jni.Value(ctx), jni.Value(uriStr), jni.Value(nameStr))
+		if err != nil {
+			return err
+		}
+
+		resultStr := jni.GoString(env, jni.String(result))
+		if err := safError(resultStr); err != nil {
+			return err
+		}
+		uri = resultStr
+		return nil
+	})
+
+	return uri, err
+}
+
+// DeleteSAFDoc deletes a document, recursively for directories
+func DeleteSAFDoc(docURI string) error {
+	var success bool
+
+	err := jni.Do(jni.JVMFor(app.JavaVM()), func(env jni.Env) error {
+		if err := initSAF(env); err != nil {
+			return err
+		}
+
+		ctx := jni.Object(app.AppContext())
+		uriStr := jni.JavaString(env, docURI)
+
+		result, err := jni.CallStaticBooleanMethod(env, safClass, deleteDocID, jni.Value(ctx), jni.Value(uriStr))
+		if err != nil {
+			return err
+		}
+		success = result
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+	if !success {
+		return errors.New("failed to delete document")
+	}
+	return nil
+}
+
+// MoveSAFDoc moves a document into targetURI and returns its new URI
+func MoveSAFDoc(treeURI, docURI, targetURI string) (string, error) {
+	var uri string
+
+	err := jni.Do(jni.JVMFor(app.JavaVM()), func(env jni.Env) error {
+		if err := initSAF(env); err != nil {
+			return err
+		}
+
+		ctx := jni.Object(app.AppContext())
+		treeStr := jni.JavaString(env, treeURI)
+		docStr := jni.JavaString(env, docURI)
+		targetStr := jni.JavaString(env, targetURI)
+
+		result, err := jni.CallStaticObjectMethod(env, safClass, moveDocID, jni.Value(ctx), jni.Value(treeStr), jni.Value(docStr), jni.Value(targetStr))
+		if err != nil {
+			return err
+		}
+
+		resultStr := jni.GoString(env, jni.String(result))
+		if err := safError(resultStr); err != nil {
+			return err
+		}
+		uri = resultStr
+		return nil
+	})
+
+	return uri, err
+}
+
+// safError converts an "ERROR:" result from the Java side into a Go error
+func safError(result string) error {
+	if msg, ok := strings.CutPrefix(result, "ERROR:"); ok {
+		return errors.New(msg)
+	}
+	return nil
 }
@@ -4,6 +4,14 @@
 
 package fs
 
+import (
+	"errors"
+	"time"
+)
+
+// errSAFUnsupported is returned by every SAF call off Android
+var errSAFUnsupported = errors.New("SAF is only available on Android")
+
 // SAFEntry represents a file or directory in SAF
 type SAFEntry struct {
 	Name  string
@@ -11,24 +19,31 @@ type SAFEntry struct {
 	IsDir bool
 }
 
+// SAFStat describes a single SAF document
+type SAFStat struct {
+	SAFEntry
+	Size    int64
+	ModTime time.Time
+}
+
 // ListSAFDir is not available on non-Android platforms
 func ListSAFDir(treeURI string) ([]SAFEntry, error) {
-	return nil, nil
+	return nil, errSAFUnsupported
 }
 
 // ListSAFSubDir is not available on non-Android platforms
 func ListSAFSubDir(treeURI, docURI string) ([]SAFEntry, error) {
-	return nil, nil
+	return nil, errSAFUnsupported
 }
 
 // ReadSAFFile is not available on non-Android platforms
 func ReadSAFFile(docURI string) ([]byte, error) {
-	return nil, nil
+	return nil, errSAFUnsupported
 }
 
 // WriteSAFFile is not available on non-Android platforms
 func WriteSAFFile(docURI string, data []byte) error {
-	return nil
+	return errSAFUnsupported
 }
 
 // GetSAFTreeName is not available on non-Android platforms
@@ -36,7 +51,27 @@ func GetSAFTreeName(treeURI string) string {
 	return ""
 }
 
-// IsSAFURI checks if a path is a SAF content URI
-func IsSAFURI(path string) bool {
-	return false
+// StatSAFDoc is not available on non-Android platforms
+func StatSAFDoc(treeURI, docURI string) (SAFStat, error) {
+	return SAFStat{}, errSAFUnsupported
+}
+
+// CreateSAFDoc is not available on non-Android platforms
+func CreateSAFDoc(treeURI, parentURI, mimeType, name string) (string, error) {
+	return "", errSAFUnsupported
+}
+
+// RenameSAFDoc is not available on non-Android platforms
+func RenameSAFDoc(docURI, name string) (string, error) {
+	return "", errSAFUnsupported
+}
+
+// DeleteSAFDoc is not available on non-Android platforms
+func DeleteSAFDoc(docURI string) error {
+	return errSAFUnsupported
+}
+
+// MoveSAFDoc is not available on non-Android platforms
+func MoveSAFDoc(treeURI, docURI, targetURI string) (string, error) {
+	return "", errSAFUnsupported
 }
@@ -0,0 +1,93 @@
+package fs
+
+import "giopad/internal/location"
+
+// SAFFS is a VaultFS over an Android Storage Access Framework tree
+type SAFFS struct {
+	TreeURI string // Root of the granted document tree
+}
+
+const (
+	safDirMIME      = "vnd.android.document/directory"
+	safMarkdownMIME = "text/markdown"
+	safBinaryMIME   = "application/octet-stream"
+)
+
+// List returns the direct children of dir
+func (s *SAFFS) List(dir string) ([]Entry, error) {
+	var safEntries []SAFEntry
+	var err error
+	if dir == s.TreeURI {
+		safEntries, err = ListSAFDir(s.TreeURI)
+	} else {
+		safEntries, err = ListSAFSubDir(s.TreeURI, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(safEntries))
+	for _, se := range safEntries {
+		entries = append(entries, Entry{Name: se.Name, Path: se.URI, IsDir: se.IsDir})
+	}
+	return entries, nil
+}
+
+// Stat returns metadata for a single document
+func (s *SAFFS) Stat(path string) (Entry, error) {
+	st, err := StatSAFDoc(s.TreeURI, path)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{
+		Name:    st.Name,
+		Path:    path,
+		IsDir:   st.IsDir,
+		Size:    st.Size,
+		ModTime: st.ModTime,
+	}
+	if path == s.TreeURI {
+		if name := GetSAFTreeName(s.TreeURI); name != "" {
+			entry.Name = name
+		}
+	}
+	return entry, nil
+}
+
+// ReadFile returns the contents of a document
+func (s *SAFFS) ReadFile(path string) ([]byte, error) {
+	return ReadSAFFile(path)
+}
+
+// WriteFile replaces the contents of a document. SAF has no rename-over,
+// so unlike OSFS this truncates and rewrites in place.
+func (s *SAFFS) WriteFile(path string, data []byte) error {
+	return WriteSAFFile(path, data)
+}
+
+// Create makes an empty document or directory inside dir
+func (s *SAFFS) Create(dir, name string, isDir bool) (string, error) {
+	// Providers may append an extension that matches the MIME type, so
+	// only claim markdown for markdown names
+	mime := safBinaryMIME
+	if isDir {
+		mime = safDirMIME
+	} else if location.IsMaybeMarkdown(name) {
+		mime = safMarkdownMIME
+	}
+	return CreateSAFDoc(s.TreeURI, dir, mime, name)
+}
+
+// Rename gives a document a new display name
+func (s *SAFFS) Rename(path, newName string) (string, error) {
+	return RenameSAFDoc(path, newName)
+}
+
+// Move puts a document into another directory of the tree
+func (s *SAFFS) Move(path, toDir string) (string, error) {
+	return MoveSAFDoc(s.TreeURI, path, toDir)
+}
+
+// Delete removes a document, recursively for directories
+func (s *SAFFS) Delete(path string) error {
+	return DeleteSAFDoc(path)
+}
@@ -0,0 +1,303 @@
+package fs
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TrashDir is the hidden folder at the vault root that deleted notes go to.
+// scanDir skips dot-directories, so it never shows up in the tree.
+const TrashDir = ".trash"
+
+// trashIndexName is the index file inside TrashDir
+const trashIndexName = "index.json"
+
+// DefaultPurgeAfterDays is how long items stay in a new trash
+const DefaultPurgeAfterDays = 30
+
+// TrashItem is one deleted file or directory. Each item lives in its own
+// slot directory, TrashDir/ID/Name, so equal names never collide.
+type TrashItem struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Path    string    `json:"path"` // original path relative to the vault root, slash separated
+	IsDir   bool      `json:"is_dir"`
+	Deleted time.Time `json:"deleted"`
+}
+
+type trashIndex struct {
+	PurgeAfterDays int         `json:"purge_after_days"` // 0 keeps items forever
+	Items          []TrashItem `json:"items"`
+}
+
+// Trash is the vault-local trash. Everything goes through VaultFS, so it
+// works the same on SAF trees as on plain directories.
+type Trash struct {
+	vfs   VaultFS
+	root  string
+	index trashIndex
+}
+
+// OpenTrash loads the trash index of the vault at root. A vault without a
+// trash yet gets an empty one; the folder is only created on first delete.
+func OpenTrash(vfs VaultFS, root string) (*Trash, error) {
+	t := &Trash{vfs: vfs, root: root}
+	t.index.PurgeAfterDays = DefaultPurgeAfterDays
+	dir, ok, err := Child(t.vfs, root, TrashDir)
+	if err != nil || !ok {
+		return t, err
+	}
+	index, ok, err := Child(t.vfs, dir.Path, trashIndexName)
+	if err != nil || !ok {
+		return t, err
+	}
+	data, err := vfs.ReadFile(index.Path)
+	if err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(data, &t.index); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// Items returns the trashed items, most recently deleted first
+func (t *Trash) Items() []TrashItem {
+	items := append([]TrashItem(nil), t.index.Items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Deleted.After(items[j].Deleted)
+	})
+	return items
+}
+
+// PurgeAfterDays returns the auto-purge age; 0 means never
+func (t *Trash) PurgeAfterDays() int {
+	return t.index.PurgeAfterDays
+}
+
+// SetPurgeAfterDays changes the auto-purge age and saves it with the index
+func (t *Trash) SetPurgeAfterDays(days int) error {
+	t.index.PurgeAfterDays = days
+	return t.save()
+}
+
+// Put moves the file or directory at p into the trash. rel is its path
+// relative to the vault root, used to put it back on restore.
+func (t *Trash) Put(p, rel string, isDir bool) error {
+	dir, err := t.dir()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	base := now.Format("20060102-150405")
+	id := base
+	var slot string
+	for i := 2; ; i++ {
+		slot, err = t.vfs.Create(dir, id, true)
+		if !errors.Is(err, os.ErrExist) {
+			break
+		}
+		id = base + "-" + strconv.Itoa(i)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := t.vfs.Move(p, slot); err != nil {
+		t.vfs.Delete(slot)
+		return err
+	}
+	t.index.Items = append(t.index.Items, TrashItem{
+		ID:      id,
+		Name:    path.Base(rel),
+		Path:    rel,
+		IsDir:   isDir,
+		Deleted: now,
+	})
+	return t.save()
+}
+
+// Restore moves an item back to where it was deleted from, recreating
+// missing folders on the way. If the name is taken by now, the item comes
+// back as "name (restored).md". Returns the restored path.
+func (t *Trash) Restore(id string) (string, error) {
+	i := t.find(id)
+	if i < 0 {
+		return "", os.ErrNotExist
+	}
+	item := t.index.Items[i]
+	dir, err := t.dir()
+	if err != nil {
+		return "", err
+	}
+	slot, ok, err := Child(t.vfs, dir, item.ID)
+	if err == nil && !ok {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		return "", err
+	}
+	entry, ok, err := Child(t.vfs, slot.Path, item.Name)
+	if err == nil && !ok {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		return "", err
+	}
+
+	dest, err := MkdirRel(t.vfs, t.root, path.Dir(item.Path))
+	if err != nil {
+		return "", err
+	}
+	name, err := t.freeName(dest, item.Name, item.IsDir)
+	if err != nil {
+		return "", err
+	}
+	src := entry.Path
+	if name != item.Name {
+		if src, err = t.vfs.Rename(src, name); err != nil {
+			return "", err
+		}
+	}
+	restored, err := t.vfs.Move(src, dest)
+	if err != nil {
+		return "", err
+	}
+	t.vfs.Delete(slot.Path)
+	t.remove(i)
+	return restored, t.save()
+}
+
+// Purge deletes an item for good
+func (t *Trash) Purge(id string) error {
+	i := t.find(id)
+	if i < 0 {
+		return os.ErrNotExist
+	}
+	if err := t.deleteSlot(id); err != nil {
+		return err
+	}
+	t.remove(i)
+	return t.save()
+}
+
+// Empty deletes every item for good
+func (t *Trash) Empty() error {
+	for len(t.index.Items) > 0 {
+		if err := t.deleteSlot(t.index.Items[0].ID); err != nil {
+			t.save()
+			return err
+		}
+		t.remove(0)
+	}
+	return t.save()
+}
+
+// AutoPurge deletes items older than the auto-purge age. Returns how many
+// went.
+func (t *Trash) AutoPurge(now time.Time) (int, error) {
+	if t.index.PurgeAfterDays <= 0 {
+		return 0, nil
+	}
+	cutoff := now.AddDate(0, 0, -t.index.PurgeAfterDays)
+	purged := 0
+	for i := 0; i < len(t.index.Items); {
+		item := t.index.Items[i]
+		if !item.Deleted.Before(cutoff) {
+			i++
+			continue
+		}
+		if err := t.deleteSlot(item.ID); err != nil {
+			return purged, errors.Join(err, t.save())
+		}
+		t.remove(i)
+		purged++
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+	return purged, t.save()
+}
+
+func (t *Trash) find(id string) int {
+	for i, item := range t.index.Items {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *Trash) remove(i int) {
+	t.index.Items = append(t.index.Items[:i], t.index.Items[i+1:]...)
+}
+
+// deleteSlot removes an item's slot directory; a slot that is already
+// gone is fine
+func (t *Trash) deleteSlot(id string) error {
+	dir, ok, err := Child(t.vfs, t.root, TrashDir)
+	if err != nil || !ok {
+		return err
+	}
+	slot, ok, err := Child(t.vfs, dir.Path, id)
+	if err != nil || !ok {
+		return err
+	}
+	return t.vfs.Delete(slot.Path)
+}
+
+// save writes the index, creating the trash folder if needed
+func (t *Trash) save() error {
+	dir, err := t.dir()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(t.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	index, ok, err := Child(t.vfs, dir, trashIndexName)
+	if err != nil {
+		return err
+	}
+	p := index.Path
+	if !ok {
+		if p, err = t.vfs.Create(dir, trashIndexName, false); err != nil {
+			return err
+		}
+	}
+	return t.vfs.WriteFile(p, data)
+}
+
+// dir returns the trash folder, creating it on first use
+func (t *Trash) dir() (string, error) {
+	dir, ok, err := Child(t.vfs, t.root, TrashDir)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return dir.Path, nil
+	}
+	return t.vfs.Create(t.root, TrashDir, true)
+}
+
+// freeName returns name, or the first "name (restored N)" not taken in dir
+func (t *Trash) freeName(dir, name string, isDir bool) (string, error) {
+	entries, err := t.vfs.List(dir)
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		taken[e.Name] = true
+	}
+	candidate := name
+	for i := 1; taken[candidate]; i++ {
+		candidate = numberedName(name, isDir, "restored", i)
+	}
+	return candidate, nil
+}
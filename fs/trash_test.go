@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"testing"
+)
+
+// newTestVault returns a MemFS with a vault at /vault holding notes/a.md
+func newTestVault(t *testing.T) *MemFS {
+	t.Helper()
+	m := NewMemFS()
+	if err := m.MkdirAll("/vault/notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteFile("/vault/notes/a.md", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestTrashPutRestore(t *testing.T) {
+	m := newTestVault(t)
+	tr, err := OpenTrash(m, "/vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Put("/vault/notes/a.md", "notes/a.md", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Stat("/vault/notes/a.md"); err == nil {
+		t.Fatal("trashed note is still in place")
+	}
+
+	// The index is saved, so a trash opened later sees the item
+	tr, err = OpenTrash(m, "/vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := tr.Items()
+	if len(items) != 1 || items[0].Path != "notes/a.md" || items[0].Name != "a.md" {
+		t.Fatalf("items = %+v", items)
+	}
+
+	// The folder went too, so restoring has to make it again
+	if err := m.Delete("/vault/notes"); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := tr.Restore(items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != "/vault/notes/a.md" {
+		t.Errorf("restored to %s", restored)
+	}
+	if data, err := m.ReadFile(restored); err != nil || string(data) != "a" {
+		t.Errorf("restored content = %q, %v", data, err)
+	}
+	if n := len(tr.Items()); n != 0 {
+		t.Errorf("%d items left after restore", n)
+	}
+}
+
+func TestTrashRestoreTaken(t *testing.T) {
+	m := newTestVault(t)
+	tr, _ := OpenTrash(m, "/vault")
+	if err := tr.Put("/vault/notes/a.md", "notes/a.md", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteFile("/vault/notes/a.md", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := tr.Restore(tr.Items()[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != "/vault/notes/a (restored).md" {
+		t.Errorf("restored to %s", restored)
+	}
+	if data, _ := m.ReadFile("/vault/notes/a.md"); string(data) != "new" {
+		t.Errorf("note in the way became %q", data)
+	}
+}
+
+func TestTrashPurge(t *testing.T) {
+	m := newTestVault(t)
+	tr, _ := OpenTrash(m, "/vault")
+	if err := tr.Put("/vault/notes", "notes", true); err != nil {
+		t.Fatal(err)
+	}
+	id := tr.Items()[0].ID
+	if err := tr.Purge(id); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(tr.Items()); n != 0 {
+		t.Errorf("%d items left after purge", n)
+	}
+	if _, ok, err := Child(m, "/vault/"+TrashDir, id); ok || err != nil {
+		t.Errorf("slot %s still there (%v)", id, err)
+	}
+	if err := tr.Purge(id); err == nil {
+		t.Error("purging twice succeeded")
+	}
+}
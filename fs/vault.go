@@ -1,219 +1,185 @@
 package fs
 
 import (
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 )
 
-// ReadFile reads a file from either filesystem or SAF URI
-func ReadFile(path string) ([]byte, error) {
-	if IsSAFURI(path) {
-		return ReadSAFFile(path)
-	}
-	return os.ReadFile(path)
-}
-
-// WriteFile writes to a file, using SAF on Android for content:// URIs
-func WriteFile(path string, data []byte) error {
-	if IsSAFURI(path) {
-		return WriteSAFFile(path, data)
-	}
-	return os.WriteFile(path, data, 0644)
-}
-
-// Node represents a file or directory in the tree
+// Node represents a file or directory in the tree. Directories start out
+// unloaded; a Loader fills in Children when the tree first needs them.
 type Node struct {
 	Path     string
 	Name     string
 	IsDir    bool
 	Depth    int
 	Children []*Node
-}
 
-// MaxDepth limits recursion depth
-const MaxDepth = 5
+	// Lazy loading state, only touched on the UI goroutine
+	Loaded  bool  // Children holds a listing of the directory
+	Loading bool  // a listing is underway in the background
+	Err     error // why the last listing failed, if it did
+	reload  bool  // list again once the current listing is in
 
-// ScanVault recursively scans a directory and returns a tree of nodes
-// Filters to only .md files and directories containing .md files
-// Supports both filesystem paths and Android SAF content:// URIs
-func ScanVault(root string) (*Node, error) {
-	// Handle Android SAF URIs
-	if IsSAFURI(root) {
-		return scanVaultSAF(root)
-	}
-
-	info, err := os.Stat(root)
-	if err != nil {
-		return nil, err
-	}
-
-	rootNode := &Node{
-		Path:  root,
-		Name:  info.Name(),
-		IsDir: true,
-		Depth: 0,
-	}
+	// Syncthing conflicts: copies grouped under their original file
+	Conflicts  []*Node
+	ConflictOf string // For a conflict copy, the original's path
+}
 
-	err = scanDir(rootNode, root, 0)
+// OpenVault returns the unloaded root node of the vault at root. Nothing
+// below it is read until a Loader lists it.
+// Works on any VaultFS: filesystem paths, Android SAF URIs or MemFS
+func OpenVault(vfs VaultFS, root string) (*Node, error) {
+	info, err := vfs.Stat(root)
 	if err != nil {
 		return nil, err
 	}
 
-	return rootNode, nil
-}
-
-// scanVaultSAF scans a SAF tree URI on Android
-func scanVaultSAF(treeURI string) (*Node, error) {
-	name := GetSAFTreeName(treeURI)
+	name := info.Name
 	if name == "" {
 		name = "Vault"
 	}
 
-	rootNode := &Node{
-		Path:  treeURI,
+	return &Node{
+		Path:  root,
 		Name:  name,
 		IsDir: true,
 		Depth: 0,
-	}
-
-	err := scanSAFDir(rootNode, treeURI, treeURI, 0)
-	if err != nil {
-		return nil, err
-	}
-
-	return rootNode, nil
+	}, nil
 }
 
-func scanSAFDir(parent *Node, treeURI, docURI string, depth int) error {
-	if depth > MaxDepth {
-		return nil
-	}
-
-	var entries []SAFEntry
-	var err error
-
-	if depth == 0 {
-		entries, err = ListSAFDir(treeURI)
-	} else {
-		entries, err = ListSAFSubDir(treeURI, docURI)
-	}
-	if err != nil {
-		return err
-	}
+// IsMarkdown reports whether a file is a note: it shows up in the tree
+// and gets indexed
+func IsMarkdown(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".md")
+}
 
-	// Sort: directories first, then alphabetically
+// sortEntries orders entries directories first, then alphabetically
+func sortEntries(entries []Entry) {
 	sort.Slice(entries, func(i, j int) bool {
 		if entries[i].IsDir != entries[j].IsDir {
-			return entries[i].IsDir
+			return entries[i].IsDir // directories first
 		}
 		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
 	})
+}
 
-	for _, entry := range entries {
-		// Skip hidden files
-		if strings.HasPrefix(entry.Name, ".") {
-			continue
+// FindNode returns the node with the given path, or nil
+func FindNode(root *Node, path string) *Node {
+	if root == nil {
+		return nil
+	}
+	if root.Path == path {
+		return root
+	}
+	for _, conflict := range root.Conflicts {
+		if conflict.Path == path {
+			return conflict
 		}
-
-		if entry.IsDir {
-			dirNode := &Node{
-				Path:  entry.URI,
-				Name:  entry.Name,
-				IsDir: true,
-				Depth: depth + 1,
-			}
-			if err := scanSAFDir(dirNode, treeURI, entry.URI, depth+1); err != nil {
-				continue
-			}
-			if hasMarkdownContent(dirNode) {
-				parent.Children = append(parent.Children, dirNode)
-			}
-		} else if strings.HasSuffix(strings.ToLower(entry.Name), ".md") {
-			fileNode := &Node{
-				Path:  entry.URI,
-				Name:  entry.Name,
-				IsDir: false,
-				Depth: depth + 1,
-			}
-			parent.Children = append(parent.Children, fileNode)
+	}
+	for _, child := range root.Children {
+		if n := FindNode(child, path); n != nil {
+			return n
 		}
 	}
-
 	return nil
 }
 
-func scanDir(parent *Node, path string, depth int) error {
-	if depth > MaxDepth {
-		return nil // Stop recursion
+// ParentPath returns the path of the directory holding path. SAF URIs
+// cannot be split, so the answer comes from the tree; OS paths outside the
+// tree fall back to filepath.Dir.
+func ParentPath(root *Node, path string) string {
+	if parent := findParent(root, path); parent != nil {
+		return parent.Path
 	}
-
-	entries, err := os.ReadDir(path)
-	if err != nil {
-		return err
+	if IsSAFURI(path) {
+		return ""
 	}
+	return filepath.Dir(path)
+}
 
-	// Sort: directories first, then alphabetically
-	sort.Slice(entries, func(i, j int) bool {
-		iDir := entries[i].IsDir()
-		jDir := entries[j].IsDir()
-		if iDir != jDir {
-			return iDir // directories first
+// NearestDir follows the slash-separated rel down from root through the
+// nodes in the tree and returns the deepest directory it reaches
+func NearestDir(root *Node, rel string) *Node {
+	n := root
+	if rel == "" || rel == "." {
+		return n
+	}
+	for _, name := range strings.Split(rel, "/") {
+		var next *Node
+		for _, child := range n.Children {
+			if child.IsDir && child.Name == name {
+				next = child
+				break
+			}
 		}
-		return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name())
-	})
-
-	for _, entry := range entries {
-		name := entry.Name()
-
-		// Skip hidden files/dirs
-		if strings.HasPrefix(name, ".") {
-			continue
+		if next == nil {
+			break
 		}
+		n = next
+	}
+	return n
+}
 
-		fullPath := filepath.Join(path, name)
-
-		if entry.IsDir() {
-			dirNode := &Node{
-				Path:  fullPath,
-				Name:  name,
-				IsDir: true,
-				Depth: depth + 1,
+// RelPath returns path relative to the vault root as slash-separated names.
+// It walks the tree, so SAF URIs work as long as the node is loaded.
+func RelPath(root *Node, path string) string {
+	var names []string
+	for path != root.Path {
+		node := FindNode(root, path)
+		if node == nil {
+			if IsSAFURI(path) {
+				break
 			}
-			// Recursively scan subdirectory
-			if err := scanDir(dirNode, fullPath, depth+1); err != nil {
-				continue // Skip dirs we can't read
+			rel, err := filepath.Rel(root.Path, path)
+			if err != nil {
+				break
 			}
-			// Only add directory if it has markdown content
-			if hasMarkdownContent(dirNode) {
-				parent.Children = append(parent.Children, dirNode)
-			}
-		} else if strings.HasSuffix(strings.ToLower(name), ".md") {
-			fileNode := &Node{
-				Path:  fullPath,
-				Name:  name,
-				IsDir: false,
-				Depth: depth + 1,
-			}
-			parent.Children = append(parent.Children, fileNode)
+			names = append([]string{filepath.ToSlash(rel)}, names...)
+			break
 		}
+		names = append([]string{node.Name}, names...)
+		path = ParentPath(root, path)
 	}
+	return strings.Join(names, "/")
+}
 
+func findParent(node *Node, path string) *Node {
+	if node == nil {
+		return nil
+	}
+	for _, child := range node.Children {
+		if child.Path == path {
+			return node
+		}
+		for _, conflict := range child.Conflicts {
+			if conflict.Path == path {
+				return node
+			}
+		}
+		if p := findParent(child, path); p != nil {
+			return p
+		}
+	}
 	return nil
 }
 
-// hasMarkdownContent checks if a directory or its children contain .md files
-func hasMarkdownContent(node *Node) bool {
-	if !node.IsDir {
-		return strings.HasSuffix(strings.ToLower(node.Name), ".md")
+// Rebase returns where path ends up after oldPath moved to newPath: newPath
+// itself, or the same relative spot below it. ok is false if path was not
+// affected. SAF URIs are opaque, so only an exact match is rebased there.
+func Rebase(path, oldPath, newPath string) (string, bool) {
+	if path == oldPath {
+		return newPath, true
 	}
-	for _, child := range node.Children {
-		if hasMarkdownContent(child) {
-			return true
+	if IsSAFURI(oldPath) {
+		return "", false
+	}
+	for _, sep := range []string{string(filepath.Separator), "/"} {
+		if strings.HasPrefix(path, oldPath+sep) {
+			return newPath + path[len(oldPath):], true
 		}
 	}
-	return false
+	return "", false
 }
 
 // FlattenTree converts a tree into a flat slice for list rendering
@@ -227,6 +193,8 @@ func FlattenTree(root *Node, expanded map[string]bool) []*Node {
 func flattenNode(node *Node, expanded map[string]bool, result *[]*Node, isRoot bool) {
 	if !isRoot {
 		*result = append(*result, node)
+		// Conflict copies always sit right under their original
+		*result = append(*result, node.Conflicts...)
 	}
 
 	if node.IsDir && (isRoot || expanded[node.Path]) {
@@ -0,0 +1,51 @@
+package fs
+
+import (
+	"strings"
+	"time"
+)
+
+// Entry describes a file or directory in a vault
+type Entry struct {
+	Name    string
+	Path    string // Backend-specific path: OS path, SAF URI or MemFS key
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+}
+
+// VaultFS is the storage a vault lives on. Paths are opaque to callers:
+// children are found through List and new paths come back from Create and
+// Rename, so SAF URIs never need to be joined by hand.
+type VaultFS interface {
+	// List returns the direct children of dir
+	List(dir string) ([]Entry, error)
+	// Stat returns metadata for a single file or directory
+	Stat(path string) (Entry, error)
+	// ReadFile returns the contents of a file
+	ReadFile(path string) ([]byte, error)
+	// WriteFile replaces the contents of a file
+	WriteFile(path string, data []byte) error
+	// Create makes an empty file or directory named name inside dir
+	Create(dir, name string, isDir bool) (string, error)
+	// Rename gives path a new base name and returns its new path
+	Rename(path, newName string) (string, error)
+	// Move puts path into the directory toDir and returns its new path
+	Move(path, toDir string) (string, error)
+	// Delete removes a file, or a directory and everything below it
+	Delete(path string) error
+}
+
+// ForVault returns the VaultFS backing a vault root:
+// SAF for content:// URIs, the OS filesystem otherwise
+func ForVault(root string) VaultFS {
+	if IsSAFURI(root) {
+		return &SAFFS{TreeURI: root}
+	}
+	return OSFS{}
+}
+
+// IsSAFURI checks if a path is a SAF content URI
+func IsSAFURI(path string) bool {
+	return strings.HasPrefix(path, "content://")
+}
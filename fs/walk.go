@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WalkMarkdown calls fn for every markdown note below dir, skipping hidden
+// files and folders. rel is the note's path relative to dir, slash
+// separated. Folders that cannot be read are skipped; an error from fn
+// stops the walk and is returned.
+func WalkMarkdown(vfs VaultFS, dir string, fn func(entry Entry, rel string) error) error {
+	return walkMarkdown(vfs, dir, "", fn)
+}
+
+func walkMarkdown(vfs VaultFS, dir, prefix string, fn func(Entry, string) error) error {
+	entries, err := vfs.List(dir)
+	if err != nil {
+		return nil // Skip dirs we can't read
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name, ".") {
+			continue
+		}
+		rel := prefix + entry.Name
+		if entry.IsDir {
+			if err := walkMarkdown(vfs, entry.Path, rel+"/", fn); err != nil {
+				return err
+			}
+		} else if IsMarkdown(entry.Name) {
+			if err := fn(entry, rel); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Child looks up name in dir. SAF paths cannot be joined, so this lists.
+func Child(vfs VaultFS, dir, name string) (Entry, bool, error) {
+	entries, err := vfs.List(dir)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for _, e := range entries {
+		if e.Name == name {
+			return e, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+// FindRel looks up the file or folder at the slash-separated rel below
+// root
+func FindRel(vfs VaultFS, root, rel string) (Entry, bool, error) {
+	if _, ok := vfs.(OSFS); ok {
+		entry, err := vfs.Stat(filepath.Join(root, filepath.FromSlash(rel)))
+		if errors.Is(err, os.ErrNotExist) {
+			return Entry{}, false, nil
+		}
+		return entry, err == nil, err
+	}
+	entry := Entry{Path: root, IsDir: true}
+	for _, name := range strings.Split(rel, "/") {
+		if !entry.IsDir {
+			return Entry{}, false, nil
+		}
+		found, ok, err := Child(vfs, entry.Path, name)
+		if err != nil || !ok {
+			return Entry{}, false, err
+		}
+		entry = found
+	}
+	return entry, true, nil
+}
+
+// MkdirRel returns the folder at the slash-separated rel below root,
+// creating whatever is missing on the way
+func MkdirRel(vfs VaultFS, root, rel string) (string, error) {
+	dir := root
+	if rel == "." || rel == "" {
+		return dir, nil
+	}
+	for _, name := range strings.Split(rel, "/") {
+		entry, ok, err := Child(vfs, dir, name)
+		switch {
+		case err != nil:
+			return "", err
+		case ok && !entry.IsDir:
+			return "", &os.PathError{Op: "mkdir", Path: entry.Path, Err: errors.New("not a directory")}
+		case ok:
+			dir = entry.Path
+		default:
+			if dir, err = vfs.Create(dir, name, true); err != nil {
+				return "", err
+			}
+		}
+	}
+	return dir, nil
+}
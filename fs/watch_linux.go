@@ -0,0 +1,211 @@
+//go:build linux
+
+package fs
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchMask selects the inotify events that can change the vault tree or
+// the contents of a note
+const watchMask = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_ONLYDIR
+
+// Watcher reports changes below a vault root using inotify.
+// Every directory is watched, except hidden ones. Directories below the
+// root are added in the background, so a large vault does not hold up
+// NewWatcher.
+type Watcher struct {
+	// Events receives the path of every entry that was created, written,
+	// removed or renamed. The root path is sent when events were dropped.
+	Events chan string
+
+	root    string
+	fd      int
+	wake    [2]int // pipe that interrupts poll on Close
+	mu      sync.Mutex
+	dirs    map[int]string // watch descriptor -> directory
+	closing chan struct{}
+	done    chan struct{}
+}
+
+// NewWatcher starts watching root, and every directory below it as the
+// background walk reaches it
+func NewWatcher(root string) (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		Events:  make(chan string, 64),
+		root:    root,
+		fd:      fd,
+		dirs:    make(map[int]string),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := unix.Pipe2(w.wake[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	if err := w.addDir(root); err != nil {
+		w.closeFDs()
+		return nil, err
+	}
+	go func() {
+		if err := w.addBelow(root); err != nil {
+			log.Printf("watch %s: %v; changes in some folders will be missed", root, err)
+		}
+		w.loop()
+	}()
+	return w, nil
+}
+
+// Close stops the watcher and closes Events
+func (w *Watcher) Close() error {
+	select {
+	case <-w.closing:
+		return nil
+	default:
+	}
+	close(w.closing)
+	unix.Write(w.wake[1], []byte{0})
+	<-w.done
+	return w.closeFDs()
+}
+
+func (w *Watcher) closeFDs() error {
+	unix.Close(w.wake[0])
+	unix.Close(w.wake[1])
+	return unix.Close(w.fd)
+}
+
+// addDir watches dir itself
+func (w *Watcher) addDir(dir string) error {
+	wd, err := unix.InotifyAddWatch(w.fd, dir, watchMask)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.dirs[wd] = dir
+	w.mu.Unlock()
+	return nil
+}
+
+// addTree watches dir and every non-hidden directory below it. It only
+// fails once the inotify watch limit is reached, as every later watch
+// would fail too.
+func (w *Watcher) addTree(dir string) error {
+	if err := w.addDir(dir); err != nil {
+		if err == unix.ENOSPC {
+			return err
+		}
+		return nil // Directory vanished or is unreadable
+	}
+	return w.addBelow(dir)
+}
+
+// addBelow watches every non-hidden directory below dir, giving up once
+// the watcher is closing
+func (w *Watcher) addBelow(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil // Directory vanished or is unreadable; its watch is enough
+	}
+	for _, entry := range entries {
+		select {
+		case <-w.closing:
+			return nil
+		default:
+		}
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			if err := w.addTree(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) loop() {
+	defer close(w.done)
+	defer close(w.Events)
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	fds := []unix.PollFd{
+		{Fd: int32(w.fd), Events: unix.POLLIN},
+		{Fd: int32(w.wake[0]), Events: unix.POLLIN},
+	}
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return
+		}
+		if fds[1].Revents != 0 {
+			return
+		}
+		n, err := unix.Read(w.fd, buf)
+		if err == unix.EAGAIN || err == unix.EINTR {
+			continue
+		}
+		if err != nil || n <= 0 {
+			return
+		}
+		for _, path := range w.parse(buf[:n]) {
+			select {
+			case w.Events <- path:
+			case <-w.closing:
+				return
+			}
+		}
+	}
+}
+
+// parse decodes a buffer of inotify events into changed paths
+func (w *Watcher) parse(buf []byte) []string {
+	var paths []string
+	for off := 0; off+unix.SizeofInotifyEvent <= len(buf); {
+		ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+		nameBytes := buf[off+unix.SizeofInotifyEvent : off+unix.SizeofInotifyEvent+int(ev.Len)]
+		off += unix.SizeofInotifyEvent + int(ev.Len)
+
+		if ev.Mask&unix.IN_Q_OVERFLOW != 0 {
+			paths = append(paths, w.root)
+			continue
+		}
+
+		w.mu.Lock()
+		dir, ok := w.dirs[int(ev.Wd)]
+		if ev.Mask&unix.IN_IGNORED != 0 {
+			delete(w.dirs, int(ev.Wd))
+		}
+		w.mu.Unlock()
+		if !ok || ev.Mask&unix.IN_IGNORED != 0 {
+			continue
+		}
+
+		name := strings.TrimRight(string(nameBytes), "\x00")
+		if name == "" {
+			paths = append(paths, dir) // Event on the directory itself
+			continue
+		}
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if ev.Mask&unix.IN_ISDIR != 0 && ev.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+			w.addTree(path)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
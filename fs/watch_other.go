@@ -0,0 +1,20 @@
+//go:build !linux
+
+package fs
+
+import "errors"
+
+// Watcher reports changes below a vault root. Only Linux is supported.
+type Watcher struct {
+	Events chan string
+}
+
+// NewWatcher is not available on this platform
+func NewWatcher(root string) (*Watcher, error) {
+	return nil, errors.New("file watching is only available on Linux")
+}
+
+// Close is a no-op on this platform
+func (w *Watcher) Close() error {
+	return nil
+}
@@ -0,0 +1,6 @@
+// Package browser hands URLs to the system's default handler
+package browser
+
+// Open shows url in the system browser, or whatever handles its scheme.
+// It is a variable so tests can catch URLs instead of opening them.
+var Open = open
@@ -0,0 +1,38 @@
+package browser
+
+import (
+	"gioui.org/app"
+	"git.wow.st/gmp/jni"
+)
+
+// flagActivityNewTask is Intent.FLAG_ACTIVITY_NEW_TASK, which starting an
+// activity from the application context needs
+const flagActivityNewTask = 0x10000000
+
+// open starts an ACTION_VIEW intent for url
+func open(url string) error {
+	return jni.Do(jni.JVMFor(app.JavaVM()), func(env jni.Env) error {
+		uriClass := jni.FindClass(env, "android/net/Uri")
+		parseID := jni.GetStaticMethodID(env, uriClass, "parse", "(Ljava/lang/String;)Landroid/net/Uri;")
+		uri, err := jni.CallStaticObjectMethod(env, uriClass, parseID, jni.Value(jni.JavaString(env, url)))
+		if err != nil {
+			return err
+		}
+
+		intentClass := jni.FindClass(env, "android/content/Intent")
+		newIntentID := jni.GetMethodID(env, intentClass, "<init>", "(Ljava/lang/String;Landroid/net/Uri;)V")
+		action := jni.JavaString(env, "android.intent.action.VIEW")
+		intent, err := jni.NewObject(env, intentClass, newIntentID, jni.Value(action), jni.Value(uri))
+		if err != nil {
+			return err
+		}
+		addFlagsID := jni.GetMethodID(env, intentClass, "addFlags", "(I)Landroid/content/Intent;")
+		if _, err := jni.CallObjectMethod(env, intent, addFlagsID, jni.Value(flagActivityNewTask)); err != nil {
+			return err
+		}
+
+		ctx := jni.Object(app.AppContext())
+		startID := jni.GetMethodID(env, jni.GetObjectClass(env, ctx), "startActivity", "(Landroid/content/Intent;)V")
+		return jni.CallVoidMethod(env, ctx, startID, jni.Value(intent))
+	})
+}
@@ -0,0 +1,9 @@
+//go:build darwin && !ios
+
+package browser
+
+import "os/exec"
+
+func open(url string) error {
+	return exec.Command("open", url).Run()
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !freebsd && !openbsd && !netbsd && !dragonfly && !windows && !(darwin && !ios)
+
+package browser
+
+import "errors"
+
+func open(url string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,14 @@
+//go:build (linux && !android) || freebsd || openbsd || netbsd || dragonfly
+
+package browser
+
+import "os/exec"
+
+func open(url string) error {
+	cmd := exec.Command("xdg-open", url)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait() // Reap it; xdg-open exits once the handler is up
+	return nil
+}
@@ -0,0 +1,12 @@
+package browser
+
+import "os/exec"
+
+func open(url string) error {
+	cmd := exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait() // Reap it; rundll32 exits once the handler is up
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package diff computes line diffs with the Myers algorithm.
+package diff
+
+// Chunk is a run of lines that are either the same on both sides or differ.
+// Equal chunks hold the shared lines in both A and B.
+type Chunk struct {
+	Equal bool
+	A     []string // Lines from the first input
+	B     []string // Lines from the second input
+}
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Lines diffs a against b and returns alternating equal and changed chunks
+func Lines(a, b []string) []Chunk {
+	var chunks []Chunk
+	for _, o := range myers(a, b) {
+		equal := o.kind == opEqual
+		if len(chunks) == 0 || chunks[len(chunks)-1].Equal != equal {
+			chunks = append(chunks, Chunk{Equal: equal})
+		}
+		c := &chunks[len(chunks)-1]
+		switch o.kind {
+		case opEqual:
+			c.A = append(c.A, o.line)
+			c.B = append(c.B, o.line)
+		case opDelete:
+			c.A = append(c.A, o.line)
+		case opInsert:
+			c.B = append(c.B, o.line)
+		}
+	}
+	return chunks
+}
+
+// myers returns the shortest edit script turning a into b. It splits the
+// problem at the middle snake and recurses on both halves, so it needs
+// space linear in the input rather than in the input times the edits.
+func myers(a, b []string) []op {
+	var ops []op
+	compare(a, b, &ops)
+	return ops
+}
+
+// compare appends the edit script turning a into b to ops
+func compare(a, b []string, ops *[]op) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		*ops = append(*ops, op{opEqual, a[0]})
+		a, b = a[1:], b[1:]
+	}
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	tail := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			*ops = append(*ops, op{opInsert, line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			*ops = append(*ops, op{opDelete, line})
+		}
+	default:
+		// Both differ at their ends, so there are at least two edits and
+		// each half has fewer
+		x, y, u, v := middleSnake(a, b)
+		compare(a[:x], b[:y], ops)
+		for _, line := range a[x:u] {
+			*ops = append(*ops, op{opEqual, line})
+		}
+		compare(a[u:], b[v:], ops)
+	}
+
+	for _, line := range tail {
+		*ops = append(*ops, op{opEqual, line})
+	}
+}
+
+// middleSnake finds the run of equal lines a[x:u] == b[y:v] halfway along
+// a shortest edit script, searching from both ends at once
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	off := max + 1
+	// fwd[off+k] is the furthest x reached on diagonal k = x-y from the
+	// start; bwd likewise from the end, counting back from n and m
+	fwd := make([]int, 2*max+3)
+	bwd := make([]int, 2*max+3)
+
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && fwd[off+k-1] < fwd[off+k+1]) {
+				x = fwd[off+k+1]
+			} else {
+				x = fwd[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			fwd[off+k] = x
+			// The backward search has gone d-1 steps; it meets this one on
+			// diagonal delta-k of its own
+			if back := delta - k; odd && back >= -(d-1) && back <= d-1 && x+bwd[off+back] >= n {
+				return sx, sy, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && bwd[off+k-1] < bwd[off+k+1]) {
+				x = bwd[off+k+1]
+			} else {
+				x = bwd[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			bwd[off+k] = x
+			if fore := delta - k; !odd && fore >= -d && fore <= d && x+fwd[off+fore] >= n {
+				return n - x, m - y, n - sx, m - sy
+			}
+		}
+	}
+	panic("diff: no middle snake")
+}
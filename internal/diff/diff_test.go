@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// lcs returns the length of the longest common subsequence of a and b
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// check verifies that chunks turn a into b, alternate between equal and
+// changed, and keep as many lines as can be kept
+func check(t *testing.T, a, b []string, chunks []Chunk) {
+	t.Helper()
+	var gotA, gotB []string
+	equal := 0
+	for i, c := range chunks {
+		if i > 0 && c.Equal == chunks[i-1].Equal {
+			t.Fatalf("chunks %d and %d are both equal=%v", i-1, i, c.Equal)
+		}
+		if c.Equal {
+			if strings.Join(c.A, "\n") != strings.Join(c.B, "\n") {
+				t.Fatalf("equal chunk %d differs: %q vs %q", i, c.A, c.B)
+			}
+			equal += len(c.A)
+		}
+		gotA = append(gotA, c.A...)
+		gotB = append(gotB, c.B...)
+	}
+	if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("chunks of %q -> %q do not rebuild the inputs", a, b)
+	}
+	if want := lcs(a, b); equal != want {
+		t.Fatalf("%q -> %q keeps %d lines, want %d", a, b, equal, want)
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"", ""},
+		{"a b c", "a b c"},
+		{"", "a b"},
+		{"a b", ""},
+		{"a b c", "a x c"},
+		{"a b c d", "b c d e"},
+		{"a b c a b b a", "c b a b a c"},
+	}
+	for _, tt := range tests {
+		a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+		check(t, a, b, Lines(a, b))
+	}
+
+	chunks := Lines(strings.Fields("a b c"), strings.Fields("a x c"))
+	if len(chunks) != 3 || chunks[1].Equal || chunks[1].A[0] != "b" || chunks[1].B[0] != "x" {
+		t.Errorf("Lines(a b c, a x c) = %+v", chunks)
+	}
+}
+
+func TestLinesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() []string {
+		s := make([]string, rng.Intn(30))
+		for i := range s {
+			s[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return s
+	}
+	for range 2000 {
+		a, b := random(), random()
+		check(t, a, b, Lines(a, b))
+	}
+}
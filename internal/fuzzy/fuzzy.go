@@ -0,0 +1,104 @@
+// Package fuzzy matches short typed patterns against names and paths.
+package fuzzy
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scoring weights. Matches at word starts and runs of adjacent matches
+// win over the same letters scattered through the text.
+const (
+	scoreMatch       = 16
+	bonusFirst       = 12 // the very first rune of text
+	bonusBoundary    = 10 // after a separator or at a camelCase hump
+	bonusConsecutive = 6  // right after the previous match
+	penaltyGap       = 1  // per skipped rune, capped by maxGapPenalty
+	maxGapPenalty    = 6
+)
+
+// Match reports whether every rune of pattern appears in text in order,
+// ignoring case. score grows with how tight and word-aligned the match is;
+// pos holds the byte offsets of the matched runes in text.
+func Match(pattern, text string) (score int, pos []int, ok bool) {
+	if pattern == "" {
+		return 0, nil, true
+	}
+	pat := []rune(pattern)
+	for i, r := range pat {
+		pat[i] = unicode.ToLower(r)
+	}
+
+	// Forward pass: the earliest place the whole pattern fits
+	end, ok := scan(pat, text)
+	if !ok {
+		return 0, nil, false
+	}
+	// Backward pass from there: the latest start for that end gives the
+	// tightest window, like "ab" in "a-x-ab" matching the last two runes
+	start := end
+	for i := len(pat) - 1; i >= 0; {
+		r, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+		if unicode.ToLower(r) == pat[i] {
+			i--
+		}
+	}
+
+	// Score the window, preferring word starts inside it
+	pos = make([]int, 0, len(pat))
+	prev := rune(-1)
+	if start > 0 {
+		prev, _ = utf8.DecodeLastRuneInString(text[:start])
+	}
+	i, gap, last := 0, 0, -2
+	for off, r := range text[start:end] {
+		off += start
+		if i < len(pat) && unicode.ToLower(r) == pat[i] {
+			score += scoreMatch
+			switch {
+			case off == 0:
+				score += bonusFirst
+			case isBoundary(prev, r):
+				score += bonusBoundary
+			}
+			if last == off-utf8.RuneLen(prev) && len(pos) > 0 {
+				score += bonusConsecutive
+			} else if gap > 0 {
+				score -= min(gap*penaltyGap, maxGapPenalty)
+			}
+			pos = append(pos, off)
+			last = off
+			gap = 0
+			i++
+		} else {
+			gap++
+		}
+		prev = r
+	}
+	return score, pos, true
+}
+
+// scan returns the byte offset just past the earliest full match
+func scan(pat []rune, text string) (int, bool) {
+	i := 0
+	for off, r := range text {
+		if unicode.ToLower(r) == pat[i] {
+			i++
+			if i == len(pat) {
+				return off + utf8.RuneLen(r), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// isBoundary reports whether r starts a word after prev
+func isBoundary(prev, r rune) bool {
+	switch prev {
+	case '/', '\\', '-', '_', ' ', '.', '(', '[':
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(r) ||
+		!unicode.IsDigit(prev) && unicode.IsDigit(r)
+}
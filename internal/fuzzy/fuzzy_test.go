@@ -0,0 +1,46 @@
+package fuzzy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		ok            bool
+		pos           []int
+	}{
+		{"", "anything", true, nil},
+		{"abc", "abc", true, []int{0, 1, 2}},
+		{"ABC", "a-b-c", true, []int{0, 2, 4}},
+		{"ab", "a-x-ab", true, []int{4, 5}},
+		{"nb", "notes/bread.md", true, []int{0, 6}},
+		{"ä", "Ärger", true, []int{0}},
+		{"cba", "abc", false, nil},
+		{"abcd", "abc", false, nil},
+	}
+	for _, tt := range tests {
+		_, pos, ok := Match(tt.pattern, tt.text)
+		if ok != tt.ok || !slices.Equal(pos, tt.pos) {
+			t.Errorf("Match(%q, %q) = %v, %v, want %v, %v", tt.pattern, tt.text, pos, ok, tt.pos, tt.ok)
+		}
+	}
+}
+
+func TestMatchScore(t *testing.T) {
+	// Each pair: the first text should score higher for the pattern
+	tests := []struct{ pattern, better, worse string }{
+		{"rm", "readme.md", "term.md"},        // first letter
+		{"pl", "project/log.md", "apple.md"},  // word starts
+		{"todo", "todo.md", "txoxdxo.md"},     // a run
+		{"note", "my notes.md", "nxoxtxe.md"}, // gaps
+	}
+	for _, tt := range tests {
+		better, _, ok1 := Match(tt.pattern, tt.better)
+		worse, _, ok2 := Match(tt.pattern, tt.worse)
+		if !ok1 || !ok2 || better <= worse {
+			t.Errorf("Match(%q): %q scores %d, %q scores %d", tt.pattern, tt.better, better, tt.worse, worse)
+		}
+	}
+}
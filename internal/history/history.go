@@ -0,0 +1,82 @@
+// Package history is a browser-style back/forward stack, kept free of UI
+// types so it can move to the shared core along with location.
+package history
+
+// maxEntries bounds the history; the oldest entries fall off first.
+const maxEntries = 100
+
+// History holds the places visited, oldest first, and which one is current.
+type History[T any] struct {
+	entries []T
+	current int
+}
+
+// Push visits e, dropping everything ahead of the current entry.
+func (h *History[T]) Push(e T) {
+	if len(h.entries) > 0 {
+		h.entries = h.entries[:h.current+1]
+	}
+	h.entries = append(h.entries, e)
+	if len(h.entries) > maxEntries {
+		h.entries = h.entries[len(h.entries)-maxEntries:]
+	}
+	h.current = len(h.entries) - 1
+}
+
+// Update replaces the current entry, e.g. to remember how far a note was
+// scrolled before leaving it. An empty history starts with e.
+func (h *History[T]) Update(e T) {
+	if len(h.entries) == 0 {
+		h.Push(e)
+		return
+	}
+	h.entries[h.current] = e
+}
+
+// Current returns the current entry, or false if the history is empty.
+func (h *History[T]) Current() (T, bool) {
+	if len(h.entries) == 0 {
+		var zero T
+		return zero, false
+	}
+	return h.entries[h.current], true
+}
+
+// CanBack returns true if there is an entry behind the current one.
+func (h *History[T]) CanBack() bool { return h.current > 0 }
+
+// CanForward returns true if there is an entry ahead of the current one.
+func (h *History[T]) CanForward() bool { return h.current < len(h.entries)-1 }
+
+// Back steps back and returns the entry there.
+func (h *History[T]) Back() (T, bool) {
+	if !h.CanBack() {
+		var zero T
+		return zero, false
+	}
+	h.current--
+	return h.entries[h.current], true
+}
+
+// Forward steps forward and returns the entry there.
+func (h *History[T]) Forward() (T, bool) {
+	if !h.CanForward() {
+		var zero T
+		return zero, false
+	}
+	h.current++
+	return h.entries[h.current], true
+}
+
+// Rewrite replaces every entry with f of it, e.g. when a note moves.
+func (h *History[T]) Rewrite(f func(T) T) {
+	for i, e := range h.entries {
+		h.entries[i] = f(e)
+	}
+}
+
+// Clear forgets every entry.
+func (h *History[T]) Clear() {
+	h.entries = nil
+	h.current = 0
+}
@@ -0,0 +1,542 @@
+// Package syntax splits source code into tokens for highlighting. Each
+// language has a small hand-written lexer; none of them parse, so odd
+// code comes out with odd colours rather than errors. Colours are up to
+// the caller.
+package syntax
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind is what a token is, as far as colouring goes
+type Kind uint8
+
+const (
+	Plain    Kind = iota
+	Keyword       // if, func, let…
+	Builtin       // built-in types, functions and constants, shell variables
+	String        // string and character literals
+	Number        // numeric literals
+	Comment       // comments
+	Function      // a name being called
+	Key           // a key of a JSON or YAML map, a Nix attribute
+	Heading       // markdown headings, diff hunk headers
+	Inserted      // added diff lines
+	Deleted       // removed diff lines
+	Meta          // decorators, diff file headers, markdown markers and links
+)
+
+// Token is a run of source text of one kind
+type Token struct {
+	Text string
+	Kind Kind
+}
+
+// aliases maps the names info strings use to the languages Tokenize knows
+var aliases = map[string]string{
+	"go":       "go",
+	"golang":   "go",
+	"nix":      "nix",
+	"sh":       "shell",
+	"bash":     "shell",
+	"shell":    "shell",
+	"zsh":      "shell",
+	"console":  "shell",
+	"json":     "json",
+	"jsonc":    "json",
+	"yaml":     "yaml",
+	"yml":      "yaml",
+	"python":   "python",
+	"py":       "python",
+	"python3":  "python",
+	"markdown": "markdown",
+	"md":       "markdown",
+	"diff":     "diff",
+	"patch":    "diff",
+}
+
+// Lang returns the language an info string, like "go" or "bash title=x",
+// names, or "" if it is not one Tokenize knows
+func Lang(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := strings.ToLower(strings.Trim(fields[0], "{}."))
+	return aliases[name]
+}
+
+// Tokenize splits src, code in lang as Lang names it, into tokens that
+// cover it exactly. Code in other languages comes back as one plain token.
+func Tokenize(lang, src string) []Token {
+	t := tokens{src: src}
+	switch lang {
+	case "diff":
+		eachLine(src, &t, diffLine)
+	case "markdown":
+		eachLine(src, &t, markdownLine)
+	case "yaml":
+		eachLine(src, &t, yamlLine)
+	default:
+		l, ok := lexers[lang]
+		if !ok {
+			t.add(src, Plain)
+			break
+		}
+		l.lex(src, &t)
+	}
+	return t.list
+}
+
+// tokens collects the tokens of src, merging neighbours of the same kind.
+// Tokens are added in order, so each text is the next stretch of src and
+// a merged token is a longer slice of it.
+type tokens struct {
+	src  string
+	end  int // where the tokens so far end in src
+	list []Token
+}
+
+func (t *tokens) add(text string, k Kind) {
+	if text == "" {
+		return
+	}
+	start := t.end
+	t.end += len(text)
+	if n := len(t.list); n > 0 && t.list[n-1].Kind == k {
+		last := &t.list[n-1]
+		last.Text = t.src[start-len(last.Text) : t.end]
+		return
+	}
+	t.list = append(t.list, Token{t.src[start:t.end], k})
+}
+
+// lexer is a configurable lexer for C-like and shell-like languages
+type lexer struct {
+	keywords     set
+	builtins     set
+	lineComments []string  // "//", "#"
+	blockComment [2]string // "/*", "*/"
+	quotes       string    // characters that open strings
+	raw          string    // of those, the ones without escapes
+	multiline    string    // of those, the ones that may span lines
+	fences       []string  // strings that open and close themselves, like """ and ''
+	identExtra   string    // characters other than letters, digits and _ in names
+	calls        bool      // mark names followed by ( as functions
+	keys         string    // mark strings and names followed by one of these as keys
+	variables    bool      // $NAME and ${NAME} are builtins
+	decorators   bool      // @name is meta
+	prefixes     string    // letters that may prefix a string, like Python's f and r
+}
+
+type set map[string]bool
+
+func words(s string) set {
+	m := make(set)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var lexers = map[string]*lexer{
+	"go": {
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto
+			if import interface map package range return select struct switch type var`),
+		builtins: words(`bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64
+			rune string uint uint8 uint16 uint32 uint64 uintptr any comparable true false nil iota
+			append cap clear close complex copy delete imag len make max min new panic print println real recover`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		raw:          "`",
+		multiline:    "`",
+		calls:        true,
+	},
+	"nix": {
+		keywords:     words(`let in with rec inherit if then else assert or`),
+		builtins:     words(`true false null builtins import derivation abort throw toString map baseNameOf dirOf`),
+		lineComments: []string{"#"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"`,
+		multiline:    `"`,
+		fences:       []string{"''"},
+		identExtra:   "-'",
+		keys:         "=",
+	},
+	"shell": {
+		keywords: words(`if then else elif fi for while until do done case esac in function select
+			return exit local export readonly declare unset break continue`),
+		builtins: words(`echo cd printf read set shift test source eval exec trap true false pwd
+			alias type command sudo`),
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		raw:          `'`,
+		multiline:    `"'`,
+		identExtra:   "-",
+		variables:    true,
+	},
+	"json": {
+		builtins:     words(`true false null`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"`,
+		keys:         ":",
+	},
+	"python": {
+		keywords: words(`and as assert async await break class continue def del elif else except
+			finally for from global if import in is lambda nonlocal not or pass raise return try
+			while with yield match case`),
+		builtins: words(`True False None self cls int str float bool bytes list dict set tuple object
+			print len range enumerate zip open type isinstance super Exception`),
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		fences:       []string{`"""`, `'''`},
+		calls:        true,
+		decorators:   true,
+		prefixes:     "rbfuRBFU",
+	},
+}
+
+// lex tokenizes src
+func (l *lexer) lex(src string, t *tokens) {
+	lineStart := true // only spaces since the start of the line
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		n, k := l.next(rest, lineStart, t)
+		if n == 0 {
+			_, n = utf8.DecodeRuneInString(rest)
+			k = Plain
+		}
+		t.add(rest[:n], k)
+		for _, c := range rest[:n] {
+			switch {
+			case c == '\n':
+				lineStart = true
+			case c != ' ' && c != '\t':
+				lineStart = false
+			}
+		}
+		i += n
+	}
+}
+
+// next returns the length and kind of the token at the start of s, or 0
+// to take one rune of plain text. t has the tokens before s.
+func (l *lexer) next(s string, lineStart bool, t *tokens) (int, Kind) {
+	for _, c := range l.lineComments {
+		// A shell # must start a word, or $# would be a comment
+		if strings.HasPrefix(s, c) && (!l.variables || lineStart || endsInSpace(t)) {
+			return lineEnd(s), Comment
+		}
+	}
+	if open, close := l.blockComment[0], l.blockComment[1]; open != "" && strings.HasPrefix(s, open) {
+		return closing(s, len(open), close), Comment
+	}
+	for _, f := range l.fences {
+		if strings.HasPrefix(s, f) {
+			return closing(s, len(f), f), String
+		}
+	}
+
+	c, size := utf8.DecodeRuneInString(s)
+	switch {
+	case strings.ContainsRune(l.quotes, c):
+		n := l.quoted(s, c)
+		return n, l.keyAfter(s, n, String)
+	case c >= '0' && c <= '9':
+		n := 1
+		for n < len(s) && (isIdent(rune(s[n])) || s[n] == '.' && n+1 < len(s) && s[n+1] >= '0' && s[n+1] <= '9') {
+			n++
+		}
+		return n, Number
+	case l.variables && c == '$':
+		return variable(s), Builtin
+	case l.decorators && c == '@' && lineStart:
+		return size + l.ident(s[size:]), Meta
+	case isIdent(c):
+		n := l.ident(s)
+		word := s[:n]
+		if l.prefixes != "" && n <= 2 && n < len(s) && strings.ContainsRune(l.quotes, rune(s[n])) &&
+			strings.Trim(word, l.prefixes) == "" {
+			return n + l.quoted(s[n:], rune(s[n])), String
+		}
+		switch {
+		case l.keywords[word]:
+			return n, Keyword
+		case l.builtins[word]:
+			return n, Builtin
+		case l.calls && strings.HasPrefix(s[n:], "("):
+			return n, Function
+		}
+		return n, l.keyAfter(s, n, Plain)
+	}
+	return 0, Plain
+}
+
+// keyAfter returns Key if the token of length n at the start of s is
+// followed by one of the lexer's key separators, and k otherwise
+func (l *lexer) keyAfter(s string, n int, k Kind) Kind {
+	if l.keys == "" {
+		return k
+	}
+	after := strings.TrimLeft(s[n:], " \t")
+	if after == "" || !strings.ContainsRune(l.keys, rune(after[0])) {
+		return k
+	}
+	if strings.HasPrefix(after, "==") {
+		return k
+	}
+	return Key
+}
+
+// ident returns the length of the name at the start of s
+func (l *lexer) ident(s string) int {
+	n := 0
+	for n < len(s) {
+		c, size := utf8.DecodeRuneInString(s[n:])
+		if !isIdent(c) && (n == 0 || !strings.ContainsRune(l.identExtra, c)) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// quoted returns the length of the string opened by q at the start of s.
+// An unclosed string ends with its line unless it may span lines.
+func (l *lexer) quoted(s string, q rune) int {
+	raw := strings.ContainsRune(l.raw, q)
+	multi := strings.ContainsRune(l.multiline, q)
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && !raw:
+			i++
+		case rune(s[i]) == q:
+			return i + 1
+		case s[i] == '\n' && !multi:
+			return i
+		}
+	}
+	return len(s)
+}
+
+// closing returns the length of s up to and including close, searched for
+// from offset from, or all of s if it never closes
+func closing(s string, from int, close string) int {
+	if i := strings.Index(s[from:], close); i >= 0 {
+		return from + i + len(close)
+	}
+	return len(s)
+}
+
+// variable returns the length of the shell variable at the start of s
+func variable(s string) int {
+	if strings.HasPrefix(s, "${") {
+		return closing(s, 2, "}")
+	}
+	n := 1
+	if n < len(s) && strings.ContainsRune("#?@*!$0123456789-", rune(s[n])) {
+		return 2
+	}
+	for n < len(s) && isIdent(rune(s[n])) {
+		n++
+	}
+	return n
+}
+
+func isIdent(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// lineEnd returns the length of s up to its first newline
+func lineEnd(s string) int {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return i
+	}
+	return len(s)
+}
+
+// endsInSpace returns true if the last token ends in a space or a tab
+func endsInSpace(t *tokens) bool {
+	if len(t.list) == 0 {
+		return true
+	}
+	last := t.list[len(t.list)-1].Text
+	return strings.HasSuffix(last, " ") || strings.HasSuffix(last, "\t")
+}
+
+// eachLine tokenizes src a line at a time with f, keeping the newlines
+func eachLine(src string, t *tokens, f func(line string, t *tokens)) {
+	for src != "" {
+		n := lineEnd(src)
+		f(src[:n], t)
+		if n < len(src) {
+			t.add("\n", Plain)
+			n++
+		}
+		src = src[n:]
+	}
+}
+
+func diffLine(line string, t *tokens) {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"),
+		strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "):
+		t.add(line, Meta)
+	case strings.HasPrefix(line, "@@"):
+		t.add(line, Heading)
+	case strings.HasPrefix(line, "+"):
+		t.add(line, Inserted)
+	case strings.HasPrefix(line, "-"):
+		t.add(line, Deleted)
+	default:
+		t.add(line, Plain)
+	}
+}
+
+func markdownLine(line string, t *tokens) {
+	trimmed := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(trimmed)]
+	t.add(indent, Plain)
+	switch {
+	case strings.HasPrefix(trimmed, "#"):
+		t.add(trimmed, Heading)
+		return
+	case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+		t.add(trimmed, Meta)
+		return
+	}
+	for _, m := range []string{"- ", "* ", "+ ", "> "} {
+		if strings.HasPrefix(trimmed, m) {
+			t.add(m, Meta)
+			trimmed = trimmed[len(m):]
+			break
+		}
+	}
+	// Code spans, then links
+	for trimmed != "" {
+		i := strings.IndexAny(trimmed, "`[")
+		if i < 0 {
+			t.add(trimmed, Plain)
+			return
+		}
+		t.add(trimmed[:i], Plain)
+		trimmed = trimmed[i:]
+		end := 0
+		if trimmed[0] == '`' {
+			end = strings.IndexByte(trimmed[1:], '`') + 2
+			if end > 1 {
+				t.add(trimmed[:end], String)
+			}
+		} else if j := strings.Index(trimmed, "]("); j > 0 {
+			if k := strings.IndexByte(trimmed[j:], ')'); k > 0 {
+				end = j + k + 1
+				t.add(trimmed[:j+1], Key)
+				t.add(trimmed[j+1:end], Meta)
+			}
+		}
+		if end <= 1 {
+			t.add(trimmed[:1], Plain)
+			end = 1
+		}
+		trimmed = trimmed[end:]
+	}
+}
+
+func yamlLine(line string, t *tokens) {
+	trimmed := strings.TrimLeft(line, " ")
+	t.add(line[:len(line)-len(trimmed)], Plain)
+	switch {
+	case trimmed == "---" || trimmed == "...":
+		t.add(trimmed, Meta)
+		return
+	case strings.HasPrefix(trimmed, "#"):
+		t.add(trimmed, Comment)
+		return
+	}
+	for strings.HasPrefix(trimmed, "- ") {
+		t.add("- ", Meta)
+		trimmed = trimmed[2:]
+	}
+	if i := yamlKeyEnd(trimmed); i > 0 {
+		t.add(trimmed[:i], Key)
+		t.add(":", Plain)
+		trimmed = trimmed[i+1:]
+	}
+	yamlValue(trimmed, t)
+}
+
+// yamlKeyEnd returns the offset of the colon after the map key that
+// starts line, or -1
+func yamlKeyEnd(line string) int {
+	if line == "" {
+		return -1
+	}
+	if q := line[0]; q == '"' || q == '\'' {
+		j := strings.IndexByte(line[1:], q)
+		if j < 0 || !strings.HasPrefix(line[j+2:], ":") {
+			return -1
+		}
+		return j + 2
+	}
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '#', '{', '[':
+			return -1
+		case ':':
+			if i+1 == len(line) || line[i+1] == ' ' {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// yamlValue tokenizes what follows a key or a list marker
+func yamlValue(s string, t *tokens) {
+	value := strings.TrimLeft(s, " ")
+	t.add(s[:len(s)-len(value)], Plain)
+	comment := ""
+	if i := strings.Index(value, " #"); i >= 0 && !strings.ContainsAny(value[:1], `"'`) {
+		value, comment = value[:i], value[i:]
+	}
+	body := strings.TrimRight(value, " ")
+	switch {
+	case body == "":
+	case body[0] == '"' || body[0] == '\'':
+		t.add(body, String)
+	case body[0] == '&' || body[0] == '*' || body[0] == '!' || body == "|" || body == ">":
+		t.add(body, Meta)
+	case yamlConstants[strings.ToLower(body)]:
+		t.add(body, Builtin)
+	case isNumber(body):
+		t.add(body, Number)
+	default:
+		t.add(body, Plain)
+	}
+	t.add(value[len(body):], Plain)
+	t.add(comment, Comment)
+}
+
+var yamlConstants = words(`true false null yes no on off ~`)
+
+func isNumber(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	if s == "" {
+		return false
+	}
+	dot := false
+	for i, c := range s {
+		switch {
+		case c >= '0' && c <= '9', c == '_':
+		case c == '.' && !dot && i > 0:
+			dot = true
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package links
+
+import (
+	"strings"
+	"unicode"
+
+	"giopad/search"
+)
+
+// Backlinks returns the lines of other notes that link to the note at
+// path, by markdown or wiki link
+func (n *Notes) Backlinks(ix *search.Index, path string) []search.Ref {
+	return ix.Backlinks(path, func(from string, l search.Link) string {
+		dest := l.Dest
+		if l.Wiki {
+			dest = WikiDest(dest)
+		}
+		if t, ok := n.Resolve(from, dest); ok && !t.External() {
+			return t.Path
+		}
+		return ""
+	})
+}
+
+// Mentions returns the lines of other notes that name the note at path
+// without linking to it
+func (n *Notes) Mentions(ix *search.Index, path string) []search.Ref {
+	return ix.Mentions(n.Title(path), path)
+}
+
+// Title returns the name of the note at path without folders or .md
+func (n *Notes) Title(path string) string {
+	name := n.WikiName(path)
+	return name[strings.LastIndexByte(name, '/')+1:]
+}
+
+// LinkMention turns the mention m, found in a note with content, into a
+// wiki link to the note at path. It returns false if the note changed so
+// that the mention is no longer where m says.
+func (n *Notes) LinkMention(content []byte, m search.Ref, path string) ([]byte, bool) {
+	lines := strings.SplitAfter(string(content), "\n")
+	if m.Line >= len(lines) || m.End > len(lines[m.Line]) {
+		return nil, false
+	}
+	line := lines[m.Line]
+	mention, title := line[m.Start:m.End], n.Title(path)
+	if letters(mention) != letters(title) {
+		return nil, false
+	}
+	link := "[[" + n.WikiName(path) + "]]"
+	if mention != title {
+		link = "[[" + n.WikiName(path) + "|" + mention + "]]"
+	}
+	lines[m.Line] = line[:m.Start] + link + line[m.End:]
+	return []byte(strings.Join(lines, "")), true
+}
+
+// letters returns the letters and digits of s in lower case, the way the
+// search index compares words
+func letters(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
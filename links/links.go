@@ -0,0 +1,142 @@
+// Package links resolves the links in a note to the notes and URLs they
+// point at
+package links
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"giopad/fs"
+	"giopad/internal/browser"
+	"giopad/search"
+)
+
+// Target is where a link points: an external URL, or a note in the vault
+// and a heading in it
+type Target struct {
+	URL    string // set for links with a scheme, like https: or mailto:
+	Path   string // the note, if it exists
+	Rel    string // vault-relative path of the note, even if missing
+	Anchor string // heading slug after the #, if any
+}
+
+// webSchemes are the schemes of links that resolve, to be opened in the
+// browser. Notes come from other devices, so other schemes, like file: or
+// a custom handler's, count as broken rather than launching something.
+var webSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// ErrScheme is why a URL with a scheme not in webSchemes is not opened
+var ErrScheme = errors.New("only http, https and mailto links open")
+
+// External returns true if the target is outside the vault
+func (t Target) External() bool {
+	return t.URL != ""
+}
+
+// Open hands the URL of an external target to the browser
+func Open(t Target) error {
+	u, err := url.Parse(t.URL)
+	if err != nil || !webSchemes[strings.ToLower(u.Scheme)] {
+		return ErrScheme
+	}
+	return browser.Open(t.URL)
+}
+
+// Notes finds notes by their vault-relative paths. A nil *Notes resolves
+// relative links of OS paths by joining them, without checking they exist.
+type Notes struct {
+	byRel  map[string]string   // vault-relative path -> note path
+	rels   map[string]string   // note path -> vault-relative path
+	byName map[string][]string // lower-case file name -> vault-relative paths
+}
+
+// NewNotes indexes notes, usually switcher.Notes, for resolving links
+func NewNotes(notes []search.Note) *Notes {
+	n := &Notes{
+		byRel:  make(map[string]string, len(notes)),
+		rels:   make(map[string]string, len(notes)),
+		byName: make(map[string][]string, len(notes)),
+	}
+	for _, note := range notes {
+		n.byRel[note.Rel] = note.Path
+		n.rels[note.Path] = note.Rel
+		name := strings.ToLower(path.Base(note.Rel))
+		n.byName[name] = append(n.byName[name], note.Rel)
+	}
+	return n
+}
+
+// Resolve returns where dest, a link destination in the note at from,
+// points. ok is false for a note that does not exist, or a URL whose
+// scheme is not in webSchemes. Wiki links, see
+// WikiDest, find their note by name anywhere in the vault.
+func (n *Notes) Resolve(from, dest string) (t Target, ok bool) {
+	dest = strings.TrimSpace(strings.Trim(dest, "<>"))
+	if name, wiki := strings.CutPrefix(dest, wikiScheme); wiki {
+		return n.resolveWiki(from, name)
+	}
+	if u, err := url.Parse(dest); err == nil && len(u.Scheme) > 1 {
+		return Target{URL: dest}, webSchemes[strings.ToLower(u.Scheme)]
+	}
+	p, anchor, _ := strings.Cut(dest, "#")
+	t.Anchor = anchor
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+	if p == "" {
+		t.Path = from
+		if n != nil {
+			t.Rel = n.rels[from]
+		}
+		return t, true
+	}
+
+	fromRel, known := "", false
+	if n != nil {
+		fromRel, known = n.rels[from]
+	}
+	if !known {
+		// A note outside the index: join OS paths and hope
+		if fs.IsSAFURI(from) || from == "" {
+			return t, false
+		}
+		t.Path = filepath.Join(filepath.Dir(from), filepath.FromSlash(p))
+		t.Rel = p
+		return t, true
+	}
+
+	rel := path.Join(path.Dir(fromRel), p)
+	if strings.HasPrefix(p, "/") {
+		rel = path.Clean(strings.TrimPrefix(p, "/"))
+	}
+	t.Rel = rel
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return t, false
+	}
+	for _, candidate := range []string{rel, rel + ".md"} {
+		if target, ok := n.byRel[candidate]; ok {
+			t.Path, t.Rel = target, candidate
+			return t, true
+		}
+	}
+	return t, false
+}
+
+// Slug turns a heading into the anchor that links to it, the way GitHub
+// does: lower case, spaces to dashes, punctuation dropped
+func Slug(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package links
+
+import (
+	"errors"
+	"testing"
+
+	"giopad/internal/browser"
+	"giopad/search"
+)
+
+// testNotes indexes notes under /v by their vault-relative paths
+func testNotes(rels ...string) *Notes {
+	var notes []search.Note
+	for _, rel := range rels {
+		notes = append(notes, search.Note{Path: "/v/" + rel, Rel: rel})
+	}
+	return NewNotes(notes)
+}
+
+func TestResolve(t *testing.T) {
+	n := testNotes("index.md", "a/b.md", "a/c d.md", "x/b.md")
+	tests := []struct {
+		from, dest string
+		want       Target
+		ok         bool
+	}{
+		{"/v/a/b.md", "c%20d.md", Target{Path: "/v/a/c d.md", Rel: "a/c d.md"}, true},
+		{"/v/a/b.md", "<c d.md>", Target{Path: "/v/a/c d.md", Rel: "a/c d.md"}, true},
+		{"/v/a/b.md", "../index.md#top", Target{Path: "/v/index.md", Rel: "index.md", Anchor: "top"}, true},
+		{"/v/a/b.md", "../index", Target{Path: "/v/index.md", Rel: "index.md"}, true},
+		{"/v/a/b.md", "/x/b.md", Target{Path: "/v/x/b.md", Rel: "x/b.md"}, true},
+		{"/v/a/b.md", "#intro", Target{Path: "/v/a/b.md", Rel: "a/b.md", Anchor: "intro"}, true},
+		{"/v/a/b.md", "missing.md", Target{Rel: "a/missing.md"}, false},
+		{"/v/index.md", "../outside.md", Target{Rel: "../outside.md"}, false},
+		{"/v/index.md", "https://example.com/a", Target{URL: "https://example.com/a"}, true},
+		{"/v/index.md", "MAILTO:me@example.com", Target{URL: "MAILTO:me@example.com"}, true},
+		{"/v/index.md", "file:///etc/passwd", Target{URL: "file:///etc/passwd"}, false},
+		{"/v/index.md", "javascript:alert(1)", Target{URL: "javascript:alert(1)"}, false},
+	}
+	for _, tt := range tests {
+		got, ok := n.Resolve(tt.from, tt.dest)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Resolve(%q, %q) = %+v, %v, want %+v, %v", tt.from, tt.dest, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResolveOutsideIndex(t *testing.T) {
+	var n *Notes
+	got, ok := n.Resolve("/home/me/notes/a.md", "b.md#x")
+	want := Target{Path: "/home/me/notes/b.md", Rel: "b.md", Anchor: "x"}
+	if !ok || got != want {
+		t.Errorf("Resolve = %+v, %v, want %+v", got, ok, want)
+	}
+	if _, ok := n.Resolve("content://tree/a.md", "b.md"); ok {
+		t.Error("resolved a relative link from a SAF note outside the index")
+	}
+}
+
+func TestResolveWiki(t *testing.T) {
+	n := testNotes("other.md", "work/x.md", "Project.md", "work/Project.md", "work/notes/Todo.md", "home/Todo.md", "deep/er/Todo.md")
+	tests := []struct {
+		from, target string
+		want         Target
+		ok           bool
+	}{
+		// Nearest first: the same folder, then the shallowest
+		{"/v/work/x.md", "Project", Target{Path: "/v/work/Project.md", Rel: "work/Project.md"}, true},
+		{"/v/other.md", "project", Target{Path: "/v/Project.md", Rel: "Project.md"}, true},
+		{"/v/other.md", "Todo", Target{Path: "/v/home/Todo.md", Rel: "home/Todo.md"}, true},
+		{"/v/other.md", "notes/Todo", Target{Path: "/v/work/notes/Todo.md", Rel: "work/notes/Todo.md"}, true},
+		{"/v/other.md", "Todo.md#Next Steps", Target{Path: "/v/home/Todo.md", Rel: "home/Todo.md", Anchor: "next-steps"}, true},
+		{"/v/other.md", "#Top", Target{Path: "/v/other.md", Rel: "other.md", Anchor: "top"}, true},
+		{"/v/other.md", "Missing", Target{Rel: "Missing.md"}, false},
+		{"/v/other.md", "../Project", Target{Rel: "../Project.md"}, false},
+	}
+	for _, tt := range tests {
+		got, ok := n.Resolve(tt.from, WikiDest(tt.target))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Resolve(%q, [[%s]]) = %+v, %v, want %+v, %v", tt.from, tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWikiName(t *testing.T) {
+	n := testNotes("Project.md", "work/Project.md", "home/Todo.md")
+	for path, want := range map[string]string{
+		"/v/home/Todo.md":    "Todo",
+		"/v/work/Project.md": "work/Project",
+		"/elsewhere/Note.md": "Note",
+	} {
+		if got := n.WikiName(path); got != want {
+			t.Errorf("WikiName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	for heading, want := range map[string]string{
+		"Next Steps":        "next-steps",
+		"  What's new? ":    "whats-new",
+		"Über_alles-2024":   "über_alles-2024",
+		"C++ & Go (basics)": "c--go-basics",
+	} {
+		if got := Slug(heading); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", heading, got, want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	var opened []string
+	defer func(open func(string) error) { browser.Open = open }(browser.Open)
+	browser.Open = func(url string) error {
+		opened = append(opened, url)
+		return nil
+	}
+
+	n := testNotes("index.md")
+	for _, dest := range []string{"https://example.com", "mailto:me@example.com", "file:///etc/passwd", "vscode://open", "ssh:host"} {
+		target, ok := n.Resolve("/v/index.md", dest)
+		err := Open(target)
+		if ok != (err == nil) {
+			t.Errorf("%s: resolved %v but Open returned %v", dest, ok, err)
+		}
+		if err != nil && !errors.Is(err, ErrScheme) {
+			t.Errorf("%s: Open returned %v", dest, err)
+		}
+	}
+	if len(opened) != 2 || opened[0] != "https://example.com" || opened[1] != "mailto:me@example.com" {
+		t.Errorf("opened %q", opened)
+	}
+}
@@ -0,0 +1,182 @@
+package links
+
+import (
+	"bytes"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// wikiScheme prefixes the destinations wiki links parse into, so Resolve
+// looks their notes up by name rather than by path
+const wikiScheme = "wiki:"
+
+// WikiDest returns the link destination for a wiki link to target, the
+// part of [[target|label]] before the bar
+func WikiDest(target string) string {
+	return wikiScheme + strings.TrimSpace(target)
+}
+
+// WikiLinks is a goldmark extension for [[Note]], [[Note|alias]] and
+// [[Note#Heading]]. They parse into ordinary links to WikiDest, so any
+// renderer that shows links shows them.
+var WikiLinks goldmark.Extender = wikiExtension{}
+
+type wikiExtension struct{}
+
+func (wikiExtension) Extend(m goldmark.Markdown) {
+	// Ahead of the link parser, which would take [[ for a bracket
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(wikiParser{}, 199)))
+}
+
+type wikiParser struct{}
+
+func (wikiParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (wikiParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, seg := block.PeekLine()
+	start, end, ok := WikiLinkAt(line)
+	if !ok || start != 0 {
+		return nil
+	}
+	inner := line[2 : end-2]
+	target, label, aliased := bytes.Cut(inner, []byte("|"))
+	labelStart := 2
+	if aliased {
+		labelStart += len(target) + 1
+	} else {
+		label = target
+	}
+	link := ast.NewLink()
+	link.Destination = []byte(WikiDest(string(target)))
+	if len(bytes.TrimSpace(label)) > 0 {
+		s := text.NewSegment(seg.Start+labelStart, seg.Start+labelStart+len(label))
+		s = s.TrimLeftSpace(block.Source())
+		link.AppendChild(link, ast.NewTextSegment(s.TrimRightSpace(block.Source())))
+	}
+	block.Advance(end)
+	return link
+}
+
+// WikiLinkAt finds the first [[...]] in line and returns its byte range.
+// The brackets must close on the same line, around something other than
+// more brackets.
+func WikiLinkAt(line []byte) (start, end int, ok bool) {
+	for off := 0; ; {
+		i := bytes.Index(line[off:], []byte("[["))
+		if i < 0 {
+			return 0, 0, false
+		}
+		i += off
+		j := bytes.Index(line[i+2:], []byte("]]"))
+		if j < 0 {
+			return 0, 0, false
+		}
+		inner := line[i+2 : i+2+j]
+		if len(bytes.TrimSpace(inner)) > 0 && !bytes.ContainsAny(inner, "[]\n") {
+			return i, i + j + 4, true
+		}
+		off = i + 1
+	}
+}
+
+// resolveWiki finds the note a wiki link names: by file name, or by the
+// end of its path if the link has slashes. Several notes of one name
+// resolve to the one nearest from.
+func (n *Notes) resolveWiki(from, dest string) (t Target, ok bool) {
+	name, heading, _ := strings.Cut(dest, "#")
+	name = strings.TrimSpace(name)
+	if heading != "" {
+		t.Anchor = Slug(heading)
+	}
+	if name == "" {
+		t.Path = from
+		if n != nil {
+			t.Rel = n.rels[from]
+		}
+		return t, true
+	}
+	rel := strings.TrimPrefix(path.Clean(name), "/")
+	if !strings.HasSuffix(strings.ToLower(rel), ".md") {
+		rel += ".md"
+	}
+	t.Rel = rel
+	if n == nil || strings.HasPrefix(rel, "../") {
+		return t, false
+	}
+
+	fromDir := path.Dir(n.rels[from])
+	best := ""
+	for _, candidate := range n.byName[strings.ToLower(path.Base(rel))] {
+		if !strings.EqualFold(candidate, rel) && !strings.HasSuffix(strings.ToLower(candidate), "/"+strings.ToLower(rel)) {
+			continue
+		}
+		if best == "" || nearer(candidate, best, fromDir) {
+			best = candidate
+		}
+	}
+	if best == "" {
+		return t, false
+	}
+	t.Path, t.Rel = n.byRel[best], best
+	return t, true
+}
+
+// nearer returns true if the note at rel a is a better match than b for a
+// link from dir: in dir itself, then the shallower one
+func nearer(a, b, dir string) bool {
+	if inA, inB := path.Dir(a) == dir, path.Dir(b) == dir; inA != inB {
+		return inA
+	}
+	if da, db := strings.Count(a, "/"), strings.Count(b, "/"); da != db {
+		return da < db
+	}
+	return a < b
+}
+
+// Names returns the name of every note, as WikiName gives it, for
+// completing wiki links
+func (n *Notes) Names() []string {
+	if n == nil {
+		return nil
+	}
+	var names []string
+	for _, rels := range n.byName {
+		for _, rel := range rels {
+			names = append(names, n.wikiName(rel))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WikiName returns what a wiki link to the note at path says: its name
+// without the .md, or its vault-relative path if other notes share that
+// name
+func (n *Notes) WikiName(path string) string {
+	rel, ok := "", false
+	if n != nil {
+		rel, ok = n.rels[path]
+	}
+	if !ok {
+		return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return n.wikiName(rel)
+}
+
+func (n *Notes) wikiName(rel string) string {
+	name := path.Base(rel)
+	if len(n.byName[strings.ToLower(name)]) > 1 {
+		name = rel
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"errors"
 	"image"
 	"io"
 	"log"
+	"maps"
 	"os"
+	pathpkg "path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"gioui.org/app"
+	"gioui.org/io/event"
 	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/system"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
@@ -16,10 +25,22 @@ import (
 	"gioui.org/unit"
 	"gioui.org/x/explorer"
 
+	"giopad/action"
 	appstate "giopad/app"
 	"giopad/fs"
+	"giopad/internal/history"
+	"giopad/links"
+	"giopad/search"
+	"giopad/ui/backlinks"
+	"giopad/ui/dialog"
 	"giopad/ui/editor"
+	"giopad/ui/merge"
+	"giopad/ui/palette"
+	"giopad/ui/searchpanel"
+	"giopad/ui/switcher"
+	"giopad/ui/tabs"
 	"giopad/ui/toolbar"
+	"giopad/ui/trash"
 	"giopad/ui/tree"
 )
 
@@ -28,6 +49,10 @@ type (
 	D = layout.Dimensions
 )
 
+// keymapPollInterval is how often keymap.json is checked for changes where
+// there is no file watcher
+const keymapPollInterval = 2 * time.Second
+
 func main() {
 	log.Println("giopad: main() starting")
 	go func() {
@@ -71,9 +96,211 @@ func run(w *app.Window) error {
 	// Initialize tree and editor
 	log.Println("giopad: initializing tree and editor")
 	fileTree := tree.New()
-	mdEditor := editor.New()
+	tabStrip := tabs.New()
+	tabStrip.Invalidate = w.Invalidate
+	active := tabStrip.Active // editor of the active tab
+	modal := dialog.New()
+	fileTree.SetDialog(modal)
+	fileTree.Invalidate = w.Invalidate
 	log.Println("giopad: tree and editor initialized")
 
+	// Settings and session live in the config dir
+	configDir, err := appstate.ConfigDir()
+	if err == nil {
+		err = os.MkdirAll(configDir, 0o755)
+	}
+	if err != nil {
+		log.Printf("config dir error: %v", err)
+		configDir = ""
+	}
+	statePath := ""
+	if configDir != "" {
+		statePath = filepath.Join(configDir, "session.json")
+	}
+	state, err := appstate.LoadState(statePath)
+	if err != nil {
+		log.Printf("session load error: %v", err)
+		if _, err := os.Stat(statePath); err == nil {
+			// Saving would write over the file that could not be read
+			statePath = ""
+		}
+	}
+	if state.Theme != "" {
+		appstate.SetDark(state.Theme != "light")
+	}
+	lastDark := appstate.CurrentTheme.IsDark
+
+	// Full-text search index of the open vault
+	var indexer *search.Indexer
+	// resyncIndex catches the index up after a tree operation. OS vaults
+	// get there through the watcher; SAF vaults have none.
+	resyncIndex := func(path string) {
+		if ix := indexer; ix != nil && fs.IsSAFURI(path) {
+			go ix.Sync()
+		}
+	}
+
+	// Back/forward history of the notes shown, and the note shown when it
+	// was last looked at; see trackNav
+	type place struct {
+		Path string
+		Pos  appstate.Position
+		Mode editor.Mode
+	}
+	var nav history.History[place]
+	var seen place
+
+	// Keep open notes attached to their files through tree operations
+	fileTree.OnRenamed = func(oldPath, newPath string) {
+		for _, ed := range tabStrip.Editors() {
+			if p, ok := fs.Rebase(ed.CurrentPath(), oldPath, newPath); ok {
+				ed.Moved(p)
+			}
+		}
+		nav.Rewrite(func(pl place) place {
+			pl.Path, _ = fs.Rebase(pl.Path, oldPath, newPath)
+			return pl
+		})
+		seen.Path, _ = fs.Rebase(seen.Path, oldPath, newPath)
+		resyncIndex(newPath)
+	}
+	fileTree.OnDeleted = func(path string) {
+		for i := tabStrip.Len() - 1; i >= 0; i-- {
+			if _, ok := fs.Rebase(tabStrip.Editors()[i].CurrentPath(), path, path); ok {
+				tabStrip.Close(i)
+			}
+		}
+		resyncIndex(path)
+	}
+
+	// saveFile saves the note open in ed. If another program changed the
+	// file since it was loaded, it asks whether to overwrite, reload or save
+	// a copy. done runs once that is settled; cancel (may be nil) if not.
+	saveFile := func(ed *editor.Editor, done, cancel func()) {
+		finish := func(err error) {
+			if err != nil {
+				log.Printf("save error: %v", err)
+				if cancel != nil {
+					cancel()
+				}
+				return
+			}
+			if ix := indexer; ix != nil {
+				go ix.Saved(ed.CurrentPath())
+			}
+			if done != nil {
+				done()
+			}
+		}
+		err := ed.Save()
+		if !errors.Is(err, editor.ErrDiskChanged) {
+			finish(err)
+			return
+		}
+		name := filepath.Base(ed.CurrentPath())
+		modal.Show("File changed on disk", name+" was changed by another program since it was opened.",
+			[]string{"Overwrite", "Reload", "Save as copy", "Cancel"}, func(choice int) {
+				switch choice {
+				case 0:
+					finish(ed.ForceSave())
+				case 1:
+					finish(ed.ReloadFromDisk())
+				case 2:
+					dir := fs.ParentPath(fileTree.Root, ed.CurrentPath())
+					path, err := ed.SaveCopy(dir)
+					if err == nil {
+						fileTree.Selected = path
+					}
+					finish(err)
+				default:
+					if cancel != nil {
+						cancel()
+					}
+				}
+			})
+	}
+
+	// guardUnsaved runs action straight away if none of eds has unsaved
+	// changes. Otherwise it asks to Save, Discard or Cancel, one note at a
+	// time; cancel undoes whatever the caller already changed and may be nil.
+	var guardUnsaved func(eds []*editor.Editor, action, cancel func())
+	guardUnsaved = func(eds []*editor.Editor, action, cancel func()) {
+		i := slices.IndexFunc(eds, (*editor.Editor).IsDirty)
+		if i < 0 {
+			action()
+			return
+		}
+		ed, rest := eds[i], eds[i+1:]
+		next := func() {
+			guardUnsaved(rest, action, cancel)
+		}
+		tabStrip.Activate(tabStrip.Find(ed.CurrentPath()))
+		name := filepath.Base(ed.CurrentPath())
+		modal.Show("Unsaved changes", name+" has unsaved changes.",
+			[]string{"Save", "Discard", "Cancel"}, func(choice int) {
+				switch choice {
+				case 0:
+					saveFile(ed, next, cancel)
+				case 1:
+					ed.Revert()
+					next()
+				default:
+					if cancel != nil {
+						cancel()
+					}
+				}
+			})
+	}
+
+	// offerRecovery asks, one note at a time, whether to restore the edits
+	// kept when the window closed under them. Later keeps them for the
+	// next time the note is opened.
+	var offerRecovery func(notes []string)
+	offerRecovery = func(notes []string) {
+		if len(notes) == 0 || configDir == "" {
+			return
+		}
+		note, rest := notes[0], notes[1:]
+		path := appstate.RecoveryPath(configDir, note)
+		content, err := os.ReadFile(path)
+		i := tabStrip.Find(note)
+		if err != nil || i < 0 {
+			offerRecovery(rest)
+			return
+		}
+		tabStrip.Activate(i)
+		name := filepath.Base(note)
+		modal.Show("Unsaved edits", name+" had unsaved changes when giopad last closed.",
+			[]string{"Restore", "Discard", "Later"}, func(choice int) {
+				switch choice {
+				case 0:
+					i := tabStrip.Find(note)
+					if i < 0 {
+						break // Closed meanwhile; keep the edits for next time
+					}
+					tabStrip.Editors()[i].Recover(content)
+					fallthrough
+				case 1:
+					if err := os.Remove(path); err != nil {
+						log.Printf("recovery error: %v", err)
+					}
+				}
+				offerRecovery(rest)
+			})
+	}
+
+	// Unsaved edits to notes about to be deleted are settled first, so the
+	// trash holds what the user meant to keep
+	fileTree.BeforeDelete = func(path string, proceed func()) {
+		var eds []*editor.Editor
+		for _, ed := range tabStrip.Editors() {
+			if _, ok := fs.Rebase(ed.CurrentPath(), path, path); ok {
+				eds = append(eds, ed)
+			}
+		}
+		guardUnsaved(eds, proceed, nil)
+	}
+
 	// File explorer - initialized lazily
 	var expl *explorer.Explorer
 	fileOpenCh := make(chan FileOpenResult, 1)
@@ -89,29 +316,386 @@ func run(w *app.Window) error {
 	// Default vault path - empty until user picks one on mobile
 	vaultPath := ""
 
-	// Scan vault
+	// Vault filesystem and watcher for external changes
+	var vfs fs.VaultFS = fs.OSFS{}
+	var watcher *fs.Watcher
+	watchCh := make(chan string, 256)
+
+	// watchVault replaces the watcher with one on path (OS vaults only)
+	watchVault := func(path string) {
+		if watcher != nil {
+			watcher.Close()
+			watcher = nil
+		}
+		if fs.IsSAFURI(path) {
+			return
+		}
+		wt, err := fs.NewWatcher(path)
+		if err != nil {
+			log.Printf("watch error: %v", err)
+			return
+		}
+		watcher = wt
+		go func() {
+			for changed := range wt.Events {
+				watchCh <- changed
+				w.Invalidate()
+			}
+		}()
+	}
+
+	// Background directory listing for the tree
+	var loader *fs.Loader
+
+	// Quick switcher; openNote shows a note and remembers it as recent.
+	// A note already open in a tab gets that tab. Otherwise it replaces the
+	// note in the active tab, unless newTab is set or that note has
+	// unsaved changes.
+	var quickSwitch *switcher.Switcher
+	openNote := func(path string, newTab bool) bool {
+		if i := tabStrip.Find(path); i >= 0 {
+			tabStrip.Activate(i)
+		} else {
+			positions := state.Vault(vaultPath).Positions
+			ed, added := active(), false
+			if ed.IsDirty() || (newTab && ed.CurrentPath() != "") {
+				ed, added = tabStrip.Add(), true
+			} else if cur := ed.CurrentPath(); cur != "" {
+				positions[cur] = ed.Position()
+			}
+			if err := ed.LoadFile(path); err != nil {
+				log.Printf("open error: %v", err)
+				if added {
+					tabStrip.Close(tabStrip.ActiveIndex())
+				}
+				return false
+			}
+			ed.SetPosition(positions[path])
+			offerRecovery([]string{path})
+		}
+		fileTree.Selected = path
+		quickSwitch.Visited(path)
+		return true
+	}
+	// createNote makes the note at rel, vault-relative, with any folders
+	// it needs, and opens it in a new tab ready for typing
+	createNote := func(rel string) {
+		root := fileTree.Root
+		if root == nil {
+			return
+		}
+		dir, err := fs.MkdirRel(vfs, root.Path, pathpkg.Dir(rel))
+		if err != nil {
+			log.Printf("create error: %v", err)
+			return
+		}
+		name := pathpkg.Base(rel)
+		entry, exists, err := fs.Child(vfs, dir, name)
+		path := entry.Path
+		if err == nil && !exists {
+			path, err = vfs.Create(dir, name, false)
+		}
+		if err != nil {
+			log.Printf("create error: %v", err)
+			return
+		}
+		// New folders may sit below the deepest one the tree knows
+		known := fs.NearestDir(root, pathpkg.Dir(rel))
+		loader.Rescan(root, known.Path)
+		fileTree.Expanded[known.Path] = true
+		resyncIndex(path)
+		if openNote(path, true) {
+			if !active().IsEditMode() {
+				active().ToggleEdit()
+			}
+			showingEditor = true
+		}
+	}
+	quickSwitch = switcher.New(func(path string) {
+		if openNote(path, true) {
+			showingEditor = true
+		}
+	}, createNote)
+
+	// Links open notes in place and URLs in the browser. They resolve
+	// against the indexed notes, rebuilt once the index settles.
+	var linkNotes *links.Notes
+	var linkIndex *search.Index
+	var linkGen uint64
+	tabStrip.Links = func() *links.Notes {
+		ix := indexer
+		if ix == nil {
+			return nil
+		}
+		gen := ix.Index.Generation()
+		if linkNotes == nil || linkIndex != ix.Index || (gen != linkGen && !ix.Busy()) {
+			linkNotes = links.NewNotes(switcher.Notes(fileTree.Root, ix.Index))
+			linkIndex, linkGen = ix.Index, gen
+		}
+		return linkNotes
+	}
+	// canCreate returns true if a missing note at rel can be made in the vault
+	canCreate := func(rel string) bool {
+		if fileTree.Root == nil || rel == "" || rel == ".." || strings.HasPrefix(rel, "../") {
+			return false
+		}
+		ext := pathpkg.Ext(rel)
+		return ext == "" || fs.IsMarkdown(rel)
+	}
+	// findNote looks for the note at rel, vault-relative, that a link
+	// missed because the index has not reached it: by name anywhere in the
+	// tree as listed so far, the way wiki links match, then on disk
+	findNote := func(rel string) (string, bool) {
+		root := fileTree.Root
+		want := strings.ToLower(rel)
+		var walk func(n *fs.Node, prefix string) string
+		walk = func(n *fs.Node, prefix string) string {
+			for _, c := range n.Children {
+				r := prefix + strings.ToLower(c.Name)
+				if c.IsDir {
+					if found := walk(c, r+"/"); found != "" {
+						return found
+					}
+				} else if r == want || strings.HasSuffix(r, "/"+want) {
+					return c.Path
+				}
+			}
+			return ""
+		}
+		if found := walk(root, ""); found != "" {
+			return found, true
+		}
+		entry, exists, err := fs.FindRel(vfs, root.Path, rel)
+		if err != nil {
+			log.Printf("find note error: %v", err)
+		}
+		return entry.Path, exists && !entry.IsDir
+	}
+	tabStrip.OnLink = func(t links.Target, ok bool) {
+		switch {
+		case t.External():
+			err := links.Open(t)
+			if errors.Is(err, links.ErrScheme) {
+				modal.Show("Link not opened", "Only http, https and mailto links open from notes:\n"+t.URL, []string{"OK"}, nil)
+			} else if errors.Is(err, errors.ErrUnsupported) {
+				modal.Show("Link not opened", "There is no browser to open links with on this system:\n"+t.URL, []string{"OK"}, nil)
+			} else if err != nil {
+				log.Printf("open link error: %v", err)
+			}
+		case !ok && canCreate(t.Rel):
+			rel := t.Rel
+			if pathpkg.Ext(rel) == "" {
+				rel += ".md"
+			}
+			if path, found := findNote(rel); found {
+				if openNote(path, false) {
+					active().GoToAnchor(t.Anchor)
+					showingEditor = true
+				}
+				return
+			}
+			if ix := indexer; ix != nil && ix.Busy() {
+				modal.Show("Still indexing", "The vault is still being indexed, so "+rel+" may exist. Try the link again in a moment.", []string{"OK"}, nil)
+				return
+			}
+			modal.Show("Create note?", "There is no note at "+rel+" yet.", []string{"Create", "Cancel"}, func(choice int) {
+				if choice == 0 {
+					createNote(rel)
+				}
+			})
+		case !ok:
+			modal.Show("Broken link", "There is no note at "+t.Rel+".", []string{"OK"}, nil)
+		case openNote(t.Path, false):
+			active().GoToAnchor(t.Anchor)
+			showingEditor = true
+		}
+	}
+
+	// Search side panel; results open the note at the matching line
+	searchPanel := searchpanel.New(func(path string, line int) {
+		if openNote(path, true) {
+			active().GoToLine(line)
+			showingEditor = true
+		}
+	})
+
+	// Backlinks side panel for the open note. Linking a mention edits the
+	// note it is in on disk, so that note must not have unsaved edits.
+	backlinksPanel := backlinks.New(func(path string, line int) {
+		if openNote(path, true) {
+			active().GoToLine(line)
+			showingEditor = true
+		}
+	}, func(m search.Ref) {
+		if i := tabStrip.Find(m.Path); i >= 0 && tabStrip.Editors()[i].IsDirty() {
+			modal.Show("Unsaved changes", m.Name+" has unsaved changes. Save it before linking in it.", []string{"OK"}, nil)
+			return
+		}
+		content, err := vfs.ReadFile(m.Path)
+		if err != nil {
+			log.Printf("link mention error: %v", err)
+			return
+		}
+		linked, ok := tabStrip.Links().LinkMention(content, m, active().CurrentPath())
+		if !ok {
+			log.Printf("link mention: %s changed since it was indexed", m.Rel)
+			return
+		}
+		if err := vfs.WriteFile(m.Path, linked); err != nil {
+			log.Printf("link mention error: %v", err)
+			return
+		}
+		if i := tabStrip.Find(m.Path); i >= 0 {
+			if err := tabStrip.Editors()[i].CheckDisk(); err != nil {
+				log.Printf("reload error: %v", err)
+			}
+		}
+		if ix := indexer; ix != nil {
+			go ix.Saved(m.Path)
+		}
+	})
+
+	// Vault trash; restored items show up in the tree again
+	var vaultTrash *fs.Trash
+	trashView := trash.New(modal, func(restored string, item fs.TrashItem) {
+		// Restoring may have recreated folders; list the deepest one known
+		dir := fs.NearestDir(fileTree.Root, pathpkg.Dir(item.Path))
+		loader.Rescan(fileTree.Root, dir.Path)
+		fileTree.Expanded[dir.Path] = true
+		resyncIndex(restored)
+	})
+	fileTree.OnShowTrash = func() {
+		if vaultTrash != nil {
+			trashView.Open(vaultTrash)
+			showingEditor = true
+		}
+	}
+
+	// saveState records the session of the open vault and writes it out
+	saveState := func() {
+		if statePath == "" {
+			return
+		}
+		state.Theme = "light"
+		if appstate.CurrentTheme.IsDark {
+			state.Theme = "dark"
+		}
+		if vaultPath != "" {
+			vs := state.Vault(vaultPath)
+			vs.SelectedFile = fileTree.Selected
+			vs.TreeExpanded = maps.Clone(fileTree.Expanded)
+			vs.RecentFiles = slices.Clone(quickSwitch.Recent())
+			vs.OpenFiles = tabStrip.Paths()
+			for _, ed := range tabStrip.Editors() {
+				if p := ed.CurrentPath(); p != "" {
+					vs.Positions[p] = ed.Position()
+				}
+			}
+		}
+		if err := state.Save(statePath); err != nil {
+			log.Printf("session save error: %v", err)
+		}
+	}
+
+	// scanVault opens the vault at path through the filesystem that backs
+	// it, putting the old vault's session away and restoring the new one's
 	scanVault := func(path string) {
 		if path == "" {
 			return
 		}
-		if root, err := fs.ScanVault(path); err == nil {
+		saveState()
+		tabStrip.CloseAll()
+		nav.Clear()
+		seen = place{}
+		vaultPath = path
+		state.UseVault(path)
+		vs := state.Vault(path)
+		fileTree.Expanded = maps.Clone(vs.TreeExpanded)
+		fileTree.Selected = vs.SelectedFile
+		quickSwitch.SetRecent(vs.RecentFiles)
+
+		vfs = fs.ForVault(path)
+		tabStrip.SetFS(vfs, path)
+		fileTree.SetFS(vfs)
+		var reopened []string
+		for _, p := range vs.OpenFiles {
+			ed := active()
+			if ed.CurrentPath() != "" {
+				ed = tabStrip.Add()
+			}
+			if err := ed.LoadFile(p); err != nil {
+				log.Printf("reopen error: %v", err)
+				tabStrip.Close(tabStrip.ActiveIndex())
+				continue
+			}
+			ed.SetPosition(vs.Positions[p])
+			reopened = append(reopened, p)
+		}
+		tabStrip.Activate(tabStrip.Find(vs.SelectedFile))
+		offerRecovery(reopened)
+		trashView.Close()
+		t, err := fs.OpenTrash(vfs, path)
+		if err != nil {
+			log.Printf("trash error: %v", err)
+		}
+		if n, err := t.AutoPurge(time.Now()); err != nil {
+			log.Printf("trash purge error: %v", err)
+		} else if n > 0 {
+			log.Printf("giopad: purged %d old items from the trash", n)
+		}
+		vaultTrash = t
+		fileTree.SetTrash(t)
+		loader = fs.NewLoader(vfs, w.Invalidate)
+		loader.MarkdownOnly = true
+		fileTree.SetLoader(loader)
+		indexer = search.NewIndexer(vfs, path, w.Invalidate)
+		searchPanel.SetIndexer(indexer)
+		backlinksPanel.SetIndexer(indexer)
+		go indexer.Sync()
+		if root, err := fs.OpenVault(vfs, path); err == nil {
 			fileTree.SetRoot(root)
+			watchVault(path)
 		}
+		saveState()
 	}
 
-	// On desktop, try default path
-	if home, err := os.UserHomeDir(); err == nil {
+	// Merge view for Syncthing conflict copies
+	mergeView := merge.New(func(original string, resolved bool) {
+		fileTree.Selected = original
+		if !resolved {
+			return
+		}
+		loader.Rescan(fileTree.Root, fs.ParentPath(fileTree.Root, original))
+		if i := tabStrip.Find(original); i >= 0 {
+			if err := tabStrip.Editors()[i].CheckDisk(); err != nil {
+				log.Printf("reload error: %v", err)
+			}
+		}
+	})
+
+	// Reopen the last vault, if it is still there, or fall back to the default
+	if last := state.VaultPath; last != "" {
+		if _, err := fs.ForVault(last).Stat(last); err == nil {
+			scanVault(last)
+		} else {
+			log.Printf("last vault gone: %v", err)
+		}
+	} else if home, err := os.UserHomeDir(); err == nil {
+		// On desktop, try default path
 		defaultPath := filepath.Join(home, "Sync", "JMC", "SideProjects")
 		if _, err := os.Stat(defaultPath); err == nil {
-			vaultPath = defaultPath
-			scanVault(vaultPath)
+			scanVault(defaultPath)
 		}
 	}
 
 	// Initialize toolbar with vault change callback
-	bottomBar := toolbar.New(vaultPath, func(newPath string) {
-		vaultPath = newPath
-		scanVault(newPath)
+	var bottomBar *toolbar.Toolbar
+	bottomBar = toolbar.New(vaultPath, func(newPath string) {
+		guardUnsaved(tabStrip.Editors(), func() {
+			scanVault(newPath)
+		}, func() {
+			bottomBar.SetVaultPath(vaultPath)
+		})
 	})
 
 	// pickVault launches native directory picker
@@ -164,6 +748,218 @@ func run(w *app.Window) error {
 		}()
 	}
 
+	// closeTab closes tab i once its unsaved changes are settled
+	closeTab := func(i int) {
+		ed := tabStrip.Editors()[i]
+		guardUnsaved([]*editor.Editor{ed}, func() {
+			if p := ed.CurrentPath(); p != "" && vaultPath != "" {
+				state.Vault(vaultPath).Positions[p] = ed.Position()
+			}
+			// The dialog may have let other tabs move
+			tabStrip.Close(slices.Index(tabStrip.Editors(), ed))
+			fileTree.Selected = active().CurrentPath()
+		}, nil)
+	}
+	tabStrip.OnClose = closeTab
+	tabStrip.OnActivate = func(ed *editor.Editor) {
+		fileTree.Selected = ed.CurrentPath()
+	}
+
+	// trackNav notices when another note is on screen than last time it
+	// looked, however it got there, and records the move in the history
+	here := func() place {
+		ed := active()
+		return place{ed.CurrentPath(), ed.Position(), ed.Mode()}
+	}
+	trackNav := func() {
+		cur := here()
+		if cur.Path != seen.Path && cur.Path != "" {
+			if seen.Path != "" {
+				nav.Update(seen)
+			}
+			nav.Push(cur)
+		}
+		seen = cur
+	}
+	// travel steps through the history to the next note that still opens
+	// and shows it the way it was left
+	travel := func(step, undo func() (place, bool)) {
+		trackNav()
+		nav.Update(seen)
+		for n := 0; ; n++ {
+			pl, ok := step()
+			if !ok {
+				for ; n > 0; n-- {
+					undo()
+				}
+				return
+			}
+			if openNote(pl.Path, false) {
+				active().SetMode(pl.Mode)
+				active().SetPosition(pl.Pos)
+				seen = here()
+				showingEditor = true
+				return
+			}
+		}
+	}
+	goBack := func() { travel(nav.Back, nav.Forward) }
+	goForward := func() { travel(nav.Forward, nav.Back) }
+
+	// Everything the keyboard and the command palette can run
+	actions := action.NewRegistry()
+	cmdPalette := palette.New(actions)
+	for _, a := range []action.Action{
+		{ID: "editor.toggleEdit", Title: "Toggle edit mode", Keys: action.Keys("Ctrl+E"), Run: func() {
+			active().ToggleEdit()
+		}},
+		{ID: "editor.toggleSplit", Title: "Toggle split preview", Keys: action.Keys("Ctrl+Shift+E"), Run: func() {
+			active().ToggleSplit()
+		}},
+		{ID: "file.save", Title: "Save note", Keys: action.Keys("Ctrl+S"), Run: func() {
+			saveFile(active(), nil, nil)
+		}},
+		{ID: "tab.close", Title: "Close tab", Keys: action.Keys("Ctrl+W"), Run: func() {
+			closeTab(tabStrip.ActiveIndex())
+		}},
+		{ID: "tab.next", Title: "Next tab", Keys: action.Keys("Ctrl+Tab"), Run: func() {
+			tabStrip.Cycle(1)
+			fileTree.Selected = active().CurrentPath()
+		}},
+		{ID: "tab.previous", Title: "Previous tab", Keys: action.Keys("Ctrl+Shift+Tab"), Run: func() {
+			tabStrip.Cycle(-1)
+			fileTree.Selected = active().CurrentPath()
+		}},
+		{ID: "history.back", Title: "Go back", Keys: action.Keys("Alt+Left"), When: nav.CanBack, Run: goBack},
+		{ID: "history.forward", Title: "Go forward", Keys: action.Keys("Alt+Right"), When: nav.CanForward, Run: goForward},
+		{ID: "switcher.open", Title: "Go to note…", Keys: action.Keys("Ctrl+P"), Run: func() {
+			var index *search.Index
+			if indexer != nil {
+				index = indexer.Index
+			}
+			quickSwitch.Open(switcher.Notes(fileTree.Root, index))
+		}},
+		{ID: "search.open", Title: "Search in vault", Keys: action.Keys("Ctrl+Shift+F"), Run: func() {
+			backlinksPanel.Close()
+			searchPanel.Open()
+			showingEditor = false
+		}},
+		{ID: "backlinks.toggle", Title: "Toggle backlinks", Keys: action.Keys("Ctrl+Shift+B"), Run: func() {
+			if backlinksPanel.Active() {
+				backlinksPanel.Close()
+				return
+			}
+			searchPanel.Close()
+			backlinksPanel.Open()
+			showingEditor = false
+		}},
+		{ID: "tree.focus", Title: "Focus file tree", Keys: action.Keys("Ctrl+Left"), Run: func() {
+			fileTree.Focused = true
+		}},
+		{ID: "editor.focus", Title: "Focus editor", Keys: action.Keys("Ctrl+Right"), Run: func() {
+			fileTree.Focused = false
+		}},
+		{ID: "view.toggleTheme", Title: "Toggle light/dark theme", Keys: action.Keys("Ctrl+T"), Run: appstate.ToggleTheme},
+		{ID: "vault.editPath", Title: "Edit vault path", Keys: action.Keys("Ctrl+D"), Run: bottomBar.TogglePathEditor},
+		{ID: "file.open", Title: "Open file…", Keys: action.Keys("Ctrl+O"), Run: openFile},
+		{ID: "vault.pick", Title: "Open vault…", Keys: action.Keys("Ctrl+Shift+O"), Run: pickVault},
+		{ID: "vault.recent", Title: "Open recent vault…", Run: func() {
+			var others []string
+			for _, p := range state.RecentVaults {
+				if p != vaultPath {
+					others = append(others, p)
+				}
+			}
+			if len(others) == 0 {
+				return
+			}
+			modal.ShowMenu("Recent vaults", others, func(choice int) {
+				if choice < 0 || choice >= len(others) {
+					return
+				}
+				guardUnsaved(tabStrip.Editors(), func() {
+					scanVault(others[choice])
+					bottomBar.SetVaultPath(vaultPath)
+				}, nil)
+			})
+		}},
+		{ID: "trash.show", Title: "Show trash", Run: fileTree.OnShowTrash},
+		{ID: "palette.open", Title: "Command palette", Keys: action.Keys("Ctrl+Shift+P"), Run: cmdPalette.Open},
+		// Quit asks about unsaved changes first
+		{ID: "app.quit", Title: "Quit", Keys: action.Keys("Ctrl+Q"), Run: func() {
+			guardUnsaved(tabStrip.Editors(), func() {
+				w.Perform(system.ActionClose)
+			}, nil)
+		}},
+	} {
+		actions.Register(a)
+	}
+	for _, a := range fileTree.Actions() {
+		actions.Register(a)
+	}
+
+	// User keymap in the config dir, reloaded whenever the file changes
+	keymapPath := ""
+	keymapCh := make(chan struct{}, 1)
+	var keymapWatcher *fs.Watcher
+	keymapChanged := func() {
+		select {
+		case keymapCh <- struct{}{}:
+		default:
+		}
+		w.Invalidate()
+	}
+	if configDir != "" {
+		keymapPath = filepath.Join(configDir, "keymap.json")
+		if wt, err := fs.NewWatcher(configDir); err == nil {
+			keymapWatcher = wt
+			go func() {
+				for changed := range wt.Events {
+					// The root comes through when events were dropped
+					if changed == keymapPath || changed == configDir {
+						keymapChanged()
+					}
+				}
+			}()
+		} else {
+			// No watcher on this platform; look at the file now and then
+			log.Printf("keymap watch: %v; polling instead", err)
+			go func() {
+				var last os.FileInfo
+				last, _ = os.Stat(keymapPath)
+				for range time.Tick(keymapPollInterval) {
+					info, _ := os.Stat(keymapPath)
+					if (info == nil) != (last == nil) || info != nil && (!info.ModTime().Equal(last.ModTime()) || info.Size() != last.Size()) {
+						keymapChanged()
+					}
+					last = info
+				}
+			}()
+		}
+	}
+	// loadKeymap applies the keymap file and reports its problems. A file
+	// that cannot be read leaves the current bindings alone.
+	loadKeymap := func() {
+		if keymapPath == "" {
+			return
+		}
+		entries, err := action.LoadKeymap(keymapPath)
+		var problems []string
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			problems = actions.SetKeymap(entries)
+		}
+		if len(problems) > 0 {
+			for _, p := range problems {
+				log.Printf("keymap: %s", p)
+			}
+			modal.Show("Keymap problems", strings.Join(problems, "\n"), []string{"OK"}, nil)
+		}
+	}
+	actions.Register(action.Action{ID: "keymap.reload", Title: "Reload keymap", Run: loadKeymap})
+	loadKeymap()
+
 	for {
 		// Check for file open results (non-blocking)
 		select {
@@ -171,19 +967,52 @@ func run(w *app.Window) error {
 			if result.Err != nil {
 				log.Printf("file open error: %v", result.Err)
 			} else if result.Path != "" {
-				mdEditor.LoadFile(result.Path)
+				openNote(result.Path, true)
 			}
+		case <-keymapCh:
+			loadKeymap()
 		case result := <-vaultPickCh:
 			if result.Err != nil {
 				log.Printf("vault pick error: %v", result.Err)
 			} else if result.VaultPath != "" {
-				vaultPath = result.VaultPath
-				scanVault(vaultPath)
-				bottomBar.SetVaultPath(vaultPath)
+				guardUnsaved(tabStrip.Editors(), func() {
+					scanVault(result.VaultPath)
+					bottomBar.SetVaultPath(vaultPath)
+				}, nil)
 			}
 		default:
 		}
 
+		// Apply external file changes, one rescan per directory per batch
+		changedDirs := make(map[string]string)
+		var changedPaths []string
+		for pending := true; pending; {
+			select {
+			case changed := <-watchCh:
+				changedDirs[filepath.Dir(changed)] = changed
+				changedPaths = append(changedPaths, changed)
+				if i := tabStrip.Find(changed); i >= 0 {
+					if err := tabStrip.Editors()[i].CheckDisk(); err != nil {
+						log.Printf("reload error: %v", err)
+					}
+				}
+			default:
+				pending = false
+			}
+		}
+		for _, changed := range changedDirs {
+			loader.Patch(fileTree.Root, changed)
+		}
+		if ix := indexer; ix != nil && len(changedPaths) > 0 {
+			go func() {
+				for _, changed := range changedPaths {
+					if err := ix.Update(changed); err != nil {
+						log.Printf("index error: %v", err)
+					}
+				}
+			}()
+		}
+
 		ev := w.Event()
 		if expl != nil {
 			expl.ListenEvents(ev)
@@ -191,95 +1020,61 @@ func run(w *app.Window) error {
 
 		switch e := ev.(type) {
 		case app.DestroyEvent:
-			return e.Err
-		case app.FrameEvent:
-			gtx := app.NewContext(&ops, e)
-			// Log first few frames to confirm rendering
-			if lastTitle == "" {
-				log.Printf("giopad: first FrameEvent, constraints=%v, metric=%+v", gtx.Constraints, gtx.Metric)
-			}
-			th := appstate.AyuMirageTheme() // Recreate each frame to pick up theme changes
-
-			// Handle key events
-			for {
-				ev, ok := gtx.Event(key.Filter{Name: "E", Required: key.ModCtrl})
-				if !ok {
-					break
-				}
-				if e, ok := ev.(key.Event); ok && e.State == key.Press {
-					mdEditor.ToggleEdit()
+			// Gio cannot veto a close from the window manager, so the
+			// Save/Discard prompt only guards Ctrl+Q. Nobody asked to save
+			// these notes, so keep the edits in the config dir until each
+			// note is next opened.
+			for _, ed := range tabStrip.Editors() {
+				if !ed.IsDirty() || configDir == "" {
+					continue
 				}
-			}
-			for {
-				ev, ok := gtx.Event(key.Filter{Name: "S", Required: key.ModCtrl})
-				if !ok {
-					break
-				}
-				if e, ok := ev.(key.Event); ok && e.State == key.Press {
-					mdEditor.Save()
+				path := appstate.RecoveryPath(configDir, ed.CurrentPath())
+				if err := ed.SaveRecovery(path); err != nil {
+					log.Printf("keep unsaved edits on close error: %v", err)
+					continue
 				}
+				log.Printf("unsaved edits to %s kept in %s", ed.CurrentPath(), path)
 			}
-			for {
-				ev, ok := gtx.Event(key.Filter{Name: key.NameLeftArrow, Required: key.ModCtrl})
-				if !ok {
-					break
-				}
-				if e, ok := ev.(key.Event); ok && e.State == key.Press {
-					fileTree.Focused = true
-				}
+			if watcher != nil {
+				watcher.Close()
 			}
-			for {
-				ev, ok := gtx.Event(key.Filter{Name: key.NameRightArrow, Required: key.ModCtrl})
-				if !ok {
-					break
-				}
-				if e, ok := ev.(key.Event); ok && e.State == key.Press {
-					fileTree.Focused = false
-				}
+			if keymapWatcher != nil {
+				keymapWatcher.Close()
 			}
-			for {
-				ev, ok := gtx.Event(key.Filter{Name: "T", Required: key.ModCtrl})
-				if !ok {
-					break
-				}
-				if e, ok := ev.(key.Event); ok && e.State == key.Press {
-					appstate.ToggleTheme()
-				}
+			saveState()
+			return e.Err
+		case app.ConfigEvent:
+			// Mobile systems may kill a backgrounded app without notice
+			if !e.Config.Focused {
+				saveState()
 			}
-			for {
-				ev, ok := gtx.Event(key.Filter{Name: "D", Required: key.ModCtrl})
-				if !ok {
-					break
-				}
-				if e, ok := ev.(key.Event); ok && e.State == key.Press {
-					bottomBar.TogglePathEditor()
-				}
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, e)
+			// While a dialog or an overlay is open it owns all input
+			modalGtx := gtx
+			if modal.Visible() || quickSwitch.Visible() || cmdPalette.Visible() {
+				gtx = gtx.Disabled()
 			}
-			for {
-				ev, ok := gtx.Event(key.Filter{Name: "O", Required: key.ModCtrl})
-				if !ok {
-					break
-				}
-				if e, ok := ev.(key.Event); ok && e.State == key.Press {
-					openFile()
-				}
+			// Log first few frames to confirm rendering
+			if lastTitle == "" {
+				log.Printf("giopad: first FrameEvent, constraints=%v, metric=%+v", gtx.Constraints, gtx.Metric)
 			}
-			// Ctrl+Shift+O for vault picker
-			for {
-				ev, ok := gtx.Event(key.Filter{Name: "O", Required: key.ModCtrl | key.ModShift})
-				if !ok {
-					break
-				}
-				if e, ok := ev.(key.Event); ok && e.State == key.Press {
-					pickVault()
-				}
+			th := appstate.AyuMirageTheme() // Recreate each frame to pick up theme changes
+			if dark := appstate.CurrentTheme.IsDark; dark != lastDark {
+				lastDark = dark
+				saveState()
 			}
 
-			// Update window title with dirty indicator
+			// Global shortcuts, after catching up with any move made since
+			// the last frame
+			trackNav()
+			actions.HandleKeys(gtx)
+
+			// Update window title with the active tab's dirty indicator
 			title := "giopad"
-			if path := mdEditor.CurrentPath(); path != "" {
+			if path := active().CurrentPath(); path != "" {
 				name := filepath.Base(path)
-				if mdEditor.IsDirty() {
+				if active().IsDirty() {
 					name += "*"
 				}
 				title = name + " - giopad"
@@ -306,13 +1101,117 @@ func run(w *app.Window) error {
 				log.Printf("giopad: screenWidthDp=%.1f, isMobile=%v, maxX=%d", screenWidthDp, isMobile, gtx.Constraints.Max.X)
 			}
 
+			// Android's back button walks back through the history, then
+			// leaves the note for the file list. On the file list nothing
+			// asks for it, so the system handles it.
+			if isMobile && showingEditor {
+				for {
+					ev, ok := gtx.Event(key.Filter{Name: key.NameBack})
+					if !ok {
+						break
+					}
+					if e, ok := ev.(key.Event); ok && e.State == key.Press {
+						if nav.CanBack() {
+							goBack()
+						} else {
+							showingEditor = false
+						}
+					}
+				}
+			}
+
+			// Mouse back/forward buttons, wherever the pointer is
+			for {
+				ev, ok := gtx.Event(pointer.Filter{Target: &nav, Kinds: pointer.Press})
+				if !ok {
+					break
+				}
+				if e, ok := ev.(pointer.Event); ok {
+					switch {
+					case e.Buttons.Contain(pointer.ButtonQuaternary):
+						goBack()
+					case e.Buttons.Contain(pointer.ButtonQuinary):
+						goForward()
+					}
+				}
+			}
+			// Everything below sits inside the area, so it still gets its
+			// own clicks
+			navArea := clip.Rect(image.Rectangle{Max: maxPt}).Push(gtx.Ops)
+			event.Op(gtx.Ops, &nav)
+
+			// Search button toggles the panel; on mobile it also brings the
+			// panel up from the editor
+			if bottomBar.SearchClicked(gtx) {
+				if searchPanel.Active() && !(isMobile && showingEditor) {
+					searchPanel.Close()
+				} else {
+					backlinksPanel.Close()
+					searchPanel.Open()
+					showingEditor = false
+				}
+			}
+
 			// Handle file selection - switch to editor on mobile
 			selected := fileTree.SelectedPath()
-			if selected != "" && selected != mdEditor.CurrentPath() {
-				mdEditor.LoadFile(selected)
-				if isMobile {
-					showingEditor = true
+			if node := fs.FindNode(fileTree.Root, selected); node != nil && node.ConflictOf != "" {
+				// Conflict copies open in the merge view
+				if selected != mergeView.ConflictPath() {
+					if err := mergeView.Open(vfs, vaultTrash, node.ConflictOf, node.Path, fs.RelPath(fileTree.Root, node.Path)); err != nil {
+						log.Printf("merge error: %v", err)
+					}
+					if isMobile {
+						showingEditor = true
+					}
+				}
+			} else if selected != "" {
+				if mergeView.Active() {
+					mergeView.Close()
+				}
+				if selected != active().CurrentPath() && !modal.Visible() {
+					trashView.Close()
+					if openNote(selected, false) {
+						if isMobile {
+							showingEditor = true
+						}
+					} else {
+						fileTree.Selected = active().CurrentPath()
+					}
+				}
+			}
+
+			// Side panel: search or backlinks while open, the file tree
+			// otherwise
+			layoutSide := func(gtx C) D {
+				if searchPanel.Active() {
+					return searchPanel.Layout(gtx, th)
+				}
+				if backlinksPanel.Active() {
+					return backlinksPanel.Layout(gtx, th, active().CurrentPath(), tabStrip.Links())
 				}
+				return fileTree.Layout(gtx, th)
+			}
+
+			// Content area: merge view while resolving a conflict, trash view
+			// while it is open
+			layoutContent := func(gtx C) D {
+				if mergeView.Active() {
+					return mergeView.Layout(gtx, th)
+				}
+				if trashView.Active() {
+					return trashView.Layout(gtx, th)
+				}
+				if tabStrip.Len() == 1 && active().CurrentPath() == "" {
+					return active().Layout(gtx, th)
+				}
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx C) D {
+						return tabStrip.Layout(gtx, th)
+					}),
+					layout.Flexed(1, func(gtx C) D {
+						return active().Layout(gtx, th)
+					}),
+				)
 			}
 
 			if isMobile {
@@ -320,6 +1219,8 @@ func run(w *app.Window) error {
 				// Handle nav button clicks before layout
 				if bottomBar.FilesClicked(gtx) {
 					showingEditor = false
+					searchPanel.Close()
+					backlinksPanel.Close()
 				}
 				if bottomBar.EditorClicked(gtx) {
 					showingEditor = true
@@ -332,12 +1233,10 @@ func run(w *app.Window) error {
 						paint.FillShape(gtx.Ops, appstate.Surface(), clip.Rect(image.Rectangle{Max: gtx.Constraints.Max}).Op())
 
 						if showingEditor {
-							return mdEditor.Layout(gtx, th)
+							return layoutContent(gtx)
 						}
 						// Tree view with padding
-						return layout.UniformInset(unit.Dp(8)).Layout(gtx, func(gtx C) D {
-							return fileTree.Layout(gtx, th)
-						})
+						return layout.UniformInset(unit.Dp(8)).Layout(gtx, layoutSide)
 					}),
 					// Bottom nav bar
 					layout.Rigid(func(gtx C) D {
@@ -363,9 +1262,7 @@ func run(w *app.Window) error {
 									Left:   unit.Dp(8),
 									Right:  unit.Dp(8),
 									Bottom: unit.Dp(8),
-								}.Layout(gtx, func(gtx C) D {
-									return fileTree.Layout(gtx, th)
-								})
+								}.Layout(gtx, layoutSide)
 							}),
 							// Toolbar at bottom
 							layout.Rigid(func(gtx C) D {
@@ -374,12 +1271,22 @@ func run(w *app.Window) error {
 						)
 					}),
 					// Content area
-					layout.Flexed(1, func(gtx C) D {
-						return mdEditor.Layout(gtx, th)
-					}),
+					layout.Flexed(1, layoutContent),
 				)
 			}
 
+			navArea.Pop()
+
+			// Overlays draw over everything but dialogs, which may ask
+			// about unsaved changes before an overlay's choice goes ahead
+			overlayGtx := modalGtx
+			if modal.Visible() {
+				overlayGtx = gtx
+			}
+			quickSwitch.Layout(overlayGtx, th)
+			cmdPalette.Layout(overlayGtx, th)
+			modal.Layout(modalGtx, th)
+
 			e.Frame(gtx.Ops)
 		}
 	}
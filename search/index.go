@@ -0,0 +1,415 @@
+// Package search keeps a full-text inverted index over the markdown notes
+// of a vault and answers queries against it
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Result is one note matching a query
+type Result struct {
+	Path    string
+	Name    string
+	Rel     string // path relative to the vault root, for display
+	Score   int
+	Matches []Match // the first few matching lines
+	Lines   int     // how many lines match in total
+}
+
+// Note is an indexed note, without its content
+type Note struct {
+	Path string
+	Name string
+	Rel  string
+}
+
+// Match is one matching line of a note
+type Match struct {
+	Line  int    // 0-based line number
+	Text  string // the line, shortened around the first hit
+	Spans []Span // byte ranges of Text to highlight
+}
+
+// Span is a byte range [Start, End) of a match's text
+type Span struct {
+	Start, End int
+}
+
+// maxMatches is how many lines a result carries
+const maxMatches = 3
+
+// snippetWidth is roughly how many bytes of a long line a match keeps
+const snippetWidth = 120
+
+// doc is one indexed note
+type doc struct {
+	path    string
+	name    string
+	rel     string
+	content string
+	size    int64
+	modTime time.Time
+	terms   map[string]int // term -> occurrences
+	links   []Link
+}
+
+// Index is an inverted index from terms to the notes containing them. It
+// is safe for concurrent use: syncing runs in the background while the UI
+// queries.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*doc
+	postings map[string]map[string]int // term -> path -> occurrences
+	linkers  map[string]map[string]int // linkName -> path -> links
+	gen      atomic.Uint64             // bumped on every change
+
+	// terms holds the terms of postings, sorted, so the terms a word starts
+	// are found by binary search. Terms new since the last search wait in
+	// added; terms left without postings are dropped when those merge in.
+	termsMu sync.Mutex
+	terms   []string
+	added   []string // appended to under mu, taken under termsMu
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]*doc),
+		postings: make(map[string]map[string]int),
+		linkers:  make(map[string]map[string]int),
+	}
+}
+
+// Len returns the number of indexed notes
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// Notes returns every indexed note, in no particular order
+func (ix *Index) Notes() []Note {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	notes := make([]Note, 0, len(ix.docs))
+	for _, d := range ix.docs {
+		notes = append(notes, Note{Path: d.path, Name: d.name, Rel: d.rel})
+	}
+	return notes
+}
+
+// Generation changes whenever the index does, so callers can tell when a
+// query is worth running again
+func (ix *Index) Generation() uint64 {
+	return ix.gen.Load()
+}
+
+// Add indexes a note, replacing whatever was indexed under path before
+func (ix *Index) Add(path, name, rel string, content []byte, size int64, modTime time.Time) {
+	d := &doc{
+		path:    path,
+		name:    name,
+		rel:     rel,
+		content: string(content),
+		size:    size,
+		modTime: modTime,
+		terms:   make(map[string]int),
+	}
+	for _, tok := range tokenize(d.content) {
+		d.terms[tok.term]++
+	}
+	d.links = parseLinks(d.content)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(path)
+	ix.docs[path] = d
+	for term, n := range d.terms {
+		p := ix.postings[term]
+		if p == nil {
+			p = make(map[string]int)
+			ix.postings[term] = p
+			ix.added = append(ix.added, term)
+		}
+		p[path] = n
+	}
+	for _, l := range d.links {
+		name := linkName(l)
+		if name == "" {
+			continue
+		}
+		p := ix.linkers[name]
+		if p == nil {
+			p = make(map[string]int)
+			ix.linkers[name] = p
+		}
+		p[path]++
+	}
+	ix.gen.Add(1)
+}
+
+// Remove drops a note from the index
+func (ix *Index) Remove(path string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.remove(path) {
+		ix.gen.Add(1)
+	}
+}
+
+// RemoveIf drops every note drop returns true for
+func (ix *Index) RemoveIf(drop func(path string) bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	changed := false
+	for path := range ix.docs {
+		if drop(path) && ix.remove(path) {
+			changed = true
+		}
+	}
+	if changed {
+		ix.gen.Add(1)
+	}
+}
+
+// remove drops path; the caller holds the write lock
+func (ix *Index) remove(path string) bool {
+	d, ok := ix.docs[path]
+	if !ok {
+		return false
+	}
+	for term := range d.terms {
+		p := ix.postings[term]
+		delete(p, path)
+		if len(p) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	for _, l := range d.links {
+		name := linkName(l)
+		if p := ix.linkers[name]; p != nil {
+			delete(p, path)
+			if len(p) == 0 {
+				delete(ix.linkers, name)
+			}
+		}
+	}
+	delete(ix.docs, path)
+	return true
+}
+
+// stamp returns what was indexed for path, to skip notes that did not
+// change since
+func (ix *Index) stamp(path string) (size int64, modTime time.Time, ok bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	d, ok := ix.docs[path]
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	return d.size, d.modTime, true
+}
+
+// relPath returns the display path path was indexed under
+func (ix *Index) relPath(path string) (string, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if d, ok := ix.docs[path]; ok {
+		return d.rel, true
+	}
+	return "", false
+}
+
+// Search returns up to limit notes containing every word of query, best
+// first. Each word also matches longer words it starts, so results show
+// up while the last word is still being typed.
+func (ix *Index) Search(query string, limit int) []Result {
+	var words []string
+	for _, tok := range tokenize(query) {
+		words = append(words, tok.term)
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	terms := ix.sortedTerms()
+
+	// Score every note that has all the words
+	var scores map[string]int
+	for _, word := range words {
+		hits := make(map[string]int)
+		for i := sort.SearchStrings(terms, word); i < len(terms) && strings.HasPrefix(terms[i], word); i++ {
+			for path, n := range ix.postings[terms[i]] {
+				if scores == nil || scores[path] > 0 {
+					hits[path] += n
+				}
+			}
+		}
+		for path := range hits {
+			if scores != nil {
+				hits[path] += scores[path]
+			}
+		}
+		scores = hits
+		if len(scores) == 0 {
+			return nil
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for path, score := range scores {
+		d := ix.docs[path]
+		name := strings.ToLower(d.name)
+		for _, word := range words {
+			if strings.Contains(name, word) {
+				score += 10 // Title hits first
+			}
+		}
+		results = append(results, Result{Path: path, Name: d.name, Rel: d.rel, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Rel < results[j].Rel
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	// Snippets only for what is shown
+	for i := range results {
+		results[i].Matches, results[i].Lines = matchLines(ix.docs[results[i].Path].content, words)
+	}
+	return results
+}
+
+// sortedTerms merges the terms added since the last call into the sorted
+// terms and returns them; the caller holds the read lock
+func (ix *Index) sortedTerms() []string {
+	ix.termsMu.Lock()
+	defer ix.termsMu.Unlock()
+	if len(ix.added) == 0 {
+		return ix.terms
+	}
+	added := append([]string(nil), ix.added...)
+	sort.Strings(added)
+	merged := make([]string, 0, len(ix.terms)+len(added))
+	keep := func(term string) {
+		if _, ok := ix.postings[term]; ok && (len(merged) == 0 || merged[len(merged)-1] != term) {
+			merged = append(merged, term)
+		}
+	}
+	i, j := 0, 0
+	for i < len(ix.terms) || j < len(added) {
+		if j == len(added) || (i < len(ix.terms) && ix.terms[i] < added[j]) {
+			keep(ix.terms[i])
+			i++
+		} else {
+			keep(added[j])
+			j++
+		}
+	}
+	ix.terms = merged
+	ix.added = nil
+	return merged
+}
+
+// matchLines finds the lines of content with a word in them and returns
+// the first maxMatches, plus how many there are
+func matchLines(content string, words []string) ([]Match, int) {
+	var matches []Match
+	total := 0
+	for i, line := range strings.Split(content, "\n") {
+		var spans []Span
+		for _, tok := range tokenize(line) {
+			for _, word := range words {
+				if strings.HasPrefix(tok.term, word) {
+					spans = append(spans, Span{tok.start, tok.end})
+					break
+				}
+			}
+		}
+		if len(spans) == 0 {
+			continue
+		}
+		total++
+		if len(matches) < maxMatches {
+			text, spans := shorten(line, spans)
+			matches = append(matches, Match{Line: i, Text: text, Spans: spans})
+		}
+	}
+	return matches, total
+}
+
+// shorten trims a long line to a window around its first span
+func shorten(line string, spans []Span) (string, []Span) {
+	line = strings.TrimRight(line, "\r")
+	if len(line) <= snippetWidth {
+		return line, spans
+	}
+	start := spans[0].Start - snippetWidth/3
+	if start < 0 {
+		start = 0
+	}
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start--
+	}
+	end := start + snippetWidth
+	if end > len(line) {
+		end = len(line)
+	}
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end--
+	}
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(line) {
+		suffix = "…"
+	}
+	shift := len(prefix) - start
+	var kept []Span
+	for _, s := range spans {
+		if s.Start >= start && s.End <= end {
+			kept = append(kept, Span{s.Start + shift, s.End + shift})
+		}
+	}
+	return prefix + line[start:end] + suffix, kept
+}
+
+// token is a lowercased word and its byte range in the original text
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into words of letters and digits
+func tokenize(text string) []token {
+	var toks []token
+	start := -1
+	for i, r := range text {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			toks = append(toks, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		toks = append(toks, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return toks
+}
@@ -0,0 +1,180 @@
+package search
+
+import (
+	"path"
+	"sort"
+	"testing"
+	"time"
+
+	"giopad/fs"
+)
+
+// newTestIndex indexes notes, vault-relative path to content, under /v
+func newTestIndex(notes map[string]string) *Index {
+	ix := NewIndex()
+	for rel, content := range notes {
+		ix.Add("/v/"+rel, rel[:len(rel)-len(".md")], rel, []byte(content), int64(len(content)), time.Time{})
+	}
+	return ix
+}
+
+func rels(results []Result) []string {
+	var s []string
+	for _, r := range results {
+		s = append(s, r.Rel)
+	}
+	return s
+}
+
+func TestSearch(t *testing.T) {
+	ix := newTestIndex(map[string]string{
+		"apples.md":  "Red apples and green pears",
+		"pears.md":   "Pears, pears and more pears",
+		"recipes.md": "Apple pie\n\nTake three apples.",
+	})
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"pears", []string{"pears.md", "apples.md"}}, // title first
+		{"appl", []string{"apples.md", "recipes.md"}},
+		{"apples green", []string{"apples.md"}},
+		{"APPLES GREEN", []string{"apples.md"}},
+		{"apples plums", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := rels(ix.Search(tt.query, 0))
+		if len(got) != len(tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+
+	r := ix.Search("take", 0)
+	if len(r) != 1 || r[0].Lines != 1 || r[0].Matches[0].Line != 2 {
+		t.Errorf("Search(take) = %+v", r)
+	}
+
+	ix.Remove("/v/recipes.md")
+	if got := rels(ix.Search("pie", 0)); got != nil {
+		t.Errorf("removed note still found: %q", got)
+	}
+	ix.Add("/v/apples.md", "apples", "apples.md", []byte("Plums now"), 9, time.Time{})
+	if got := rels(ix.Search("green", 0)); got != nil {
+		t.Errorf("replaced content still found: %q", got)
+	}
+	if got := rels(ix.Search("plu", 1)); len(got) != 1 || got[0] != "apples.md" {
+		t.Errorf("Search(plu) = %q", got)
+	}
+}
+
+func TestBacklinks(t *testing.T) {
+	ix := newTestIndex(map[string]string{
+		"target.md":     "# Target\n[self](target.md)",
+		"a.md":          "See [the target](target.md#intro) and [[Target]].",
+		"sub/b.md":      "Up: [t](../target.md)\n`[[Target]]` in code",
+		"c.md":          "```\n[[Target]]\n```\n[[Other]]",
+		"sub/target.md": "A note of the same name",
+	})
+	resolve := func(from string, l Link) string {
+		if l.Dest == "Target" || l.Dest == "target.md#intro" || l.Dest == "../target.md" {
+			return "/v/target.md"
+		}
+		return ""
+	}
+	refs := ix.Backlinks("/v/target.md", resolve)
+	var got []string
+	for _, r := range refs {
+		got = append(got, r.Rel+":"+r.Text[r.Spans[0].Start:r.Spans[0].End])
+	}
+	sort.Strings(got)
+	want := []string{"a.md:[[Target]]", "a.md:[the target](target.md#intro)", "sub/b.md:[t](../target.md)"}
+	if len(got) != len(want) {
+		t.Fatalf("Backlinks = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Backlinks = %q, want %q", got, want)
+			break
+		}
+	}
+
+	ix.Remove("/v/a.md")
+	if refs := ix.Backlinks("/v/target.md", resolve); len(refs) != 1 {
+		t.Errorf("%d backlinks after removing a linking note", len(refs))
+	}
+	if refs := ix.Backlinks("/v/missing.md", resolve); refs != nil {
+		t.Errorf("backlinks of a note not indexed: %+v", refs)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	ix := newTestIndex(map[string]string{
+		"project plan.md": "The plan",
+		"a.md":            "Read the Project  Plan today.\n[[Project Plan]] is linked",
+		"b.md":            "`project plan` in code, projects planned",
+		"c.md":            "```\nproject plan\n```\nproject-plan",
+	})
+	refs := ix.Mentions("Project Plan", "/v/project plan.md")
+	var got []string
+	for _, r := range refs {
+		got = append(got, r.Rel)
+	}
+	if len(got) != 2 || got[0] != "a.md" || got[1] != "c.md" {
+		t.Fatalf("Mentions = %q", got)
+	}
+	if r := refs[0]; r.Line != 0 || r.Start != 9 || r.End != 22 {
+		t.Errorf("mention in a.md at line %d [%d, %d)", r.Line, r.Start, r.End)
+	}
+	if r := refs[1]; r.Line != 3 {
+		t.Errorf("mention in c.md at line %d", r.Line)
+	}
+}
+
+func TestIndexerSync(t *testing.T) {
+	m := fs.NewMemFS()
+	for p, content := range map[string]string{
+		"/v/a.md":         "alpha",
+		"/v/sub/b.md":     "beta",
+		"/v/notes.txt":    "alpha",
+		"/v/.trash/c.md":  "alpha",
+		"/v/sub/d.md.bak": "alpha",
+	} {
+		if err := m.MkdirAll(path.Dir(p)); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.WriteFile(p, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	x := NewIndexer(m, "/v", nil)
+	if err := x.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if x.Busy() {
+		t.Error("busy after Sync")
+	}
+	if got := rels(x.Index.Search("alpha", 0)); len(got) != 1 || got[0] != "a.md" {
+		t.Errorf("Search(alpha) = %q", got)
+	}
+	if got := rels(x.Index.Search("beta", 0)); len(got) != 1 || got[0] != "sub/b.md" {
+		t.Errorf("Search(beta) = %q", got)
+	}
+
+	if err := m.Delete("/v/a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if n := x.Index.Len(); n != 1 {
+		t.Errorf("%d notes after deleting one of two", n)
+	}
+}
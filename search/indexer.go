@@ -0,0 +1,163 @@
+package search
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"giopad/fs"
+)
+
+// progressEvery is how many notes Sync reads between UI refreshes
+const progressEvery = 200
+
+// Indexer keeps an Index in step with the notes of one vault. Its methods
+// read the vault, so call them off the UI goroutine; they take turns.
+type Indexer struct {
+	Index *Index
+
+	vfs        fs.VaultFS
+	root       string
+	invalidate func()
+	mu         sync.Mutex
+	busy       atomic.Bool
+}
+
+// NewIndexer creates an indexer for the vault at root with an empty index.
+// invalidate is called as the index fills up.
+func NewIndexer(vfs fs.VaultFS, root string, invalidate func()) *Indexer {
+	return &Indexer{
+		Index:      NewIndex(),
+		vfs:        vfs,
+		root:       root,
+		invalidate: invalidate,
+	}
+}
+
+// Busy returns true while the indexer is walking the vault
+func (x *Indexer) Busy() bool {
+	return x.busy.Load()
+}
+
+// Sync walks the whole vault, reading only notes that changed since they
+// were indexed and dropping the ones that are gone. The first Sync builds
+// the index; later ones catch up with changes no watcher reported, such
+// as on SAF vaults.
+func (x *Indexer) Sync() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.busy.Store(true)
+	defer x.busy.Store(false)
+	defer x.refreshUI()
+
+	seen := make(map[string]bool)
+	read := 0
+	err := fs.WalkMarkdown(x.vfs, x.root, func(entry fs.Entry, rel string) error {
+		seen[entry.Path] = true
+		if x.refresh(entry, rel) {
+			if read++; read%progressEvery == 0 {
+				x.refreshUI()
+			}
+		}
+		return nil
+	})
+	x.Index.RemoveIf(func(path string) bool {
+		return !seen[path]
+	})
+	return err
+}
+
+// Update re-indexes an OS path the watcher reported: a note, a folder and
+// everything below it, or something that is gone
+func (x *Indexer) Update(path string) error {
+	rel, err := filepath.Rel(x.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	for _, name := range strings.Split(rel, "/") {
+		if strings.HasPrefix(name, ".") {
+			return nil // Hidden, like the trash
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.refreshUI()
+
+	under := func(p string) bool {
+		return p == path || strings.HasPrefix(p, path+string(filepath.Separator))
+	}
+	info, err := x.vfs.Stat(path)
+	switch {
+	case err != nil:
+		x.Index.RemoveIf(under)
+	case info.IsDir:
+		seen := make(map[string]bool)
+		prefix := rel + "/"
+		if rel == "." {
+			prefix = ""
+		}
+		err = fs.WalkMarkdown(x.vfs, path, func(entry fs.Entry, r string) error {
+			seen[entry.Path] = true
+			x.refresh(entry, prefix+r)
+			return nil
+		})
+		x.Index.RemoveIf(func(p string) bool {
+			return under(p) && !seen[p]
+		})
+		return err
+	case fs.IsMarkdown(info.Name):
+		x.refresh(info, rel)
+	}
+	return nil
+}
+
+// Saved re-indexes a note giopad just wrote. SAF vaults have no watcher,
+// so this is how their saves reach the index.
+func (x *Indexer) Saved(path string) {
+	info, err := x.vfs.Stat(path)
+	if err != nil || !fs.IsMarkdown(info.Name) {
+		return
+	}
+	content, err := x.vfs.ReadFile(path)
+	if err != nil {
+		return
+	}
+	rel := info.Name
+	if r, ok := x.Index.relPath(path); ok {
+		rel = r
+	} else if r, err := filepath.Rel(x.root, path); err == nil && !fs.IsSAFURI(path) {
+		rel = filepath.ToSlash(r)
+	}
+	x.Index.Add(path, info.Name, rel, content, info.Size, info.ModTime)
+	x.refreshUI()
+}
+
+// refresh reads and indexes a note unless it is unchanged since last time.
+// Returns true if the note was read.
+func (x *Indexer) refresh(entry fs.Entry, rel string) bool {
+	if entry.ModTime.IsZero() {
+		// SAF listings carry no times; ask for them
+		if info, err := x.vfs.Stat(entry.Path); err == nil {
+			entry.Size, entry.ModTime = info.Size, info.ModTime
+		}
+	}
+	size, modTime, ok := x.Index.stamp(entry.Path)
+	if ok && !entry.ModTime.IsZero() && size == entry.Size && modTime.Equal(entry.ModTime) {
+		return false
+	}
+	data, err := x.vfs.ReadFile(entry.Path)
+	if err != nil {
+		return false
+	}
+	x.Index.Add(entry.Path, entry.Name, rel, data, entry.Size, entry.ModTime)
+	return true
+}
+
+func (x *Indexer) refreshUI() {
+	if x.invalidate != nil {
+		x.invalidate()
+	}
+}
@@ -0,0 +1,316 @@
+package search
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxRefs caps how many backlinks or mentions of one note are listed
+const maxRefs = 200
+
+// Link is a link in a note, as written. Resolving it to a note is up to
+// the caller, who knows about the vault's notes.
+type Link struct {
+	Dest       string // destination; for wiki links the target before any |
+	Wiki       bool   // a [[wiki link]]
+	Line       int    // 0-based line number
+	Start, End int    // byte range of the whole link in its line
+}
+
+// Ref is a line of one note that refers to another, by a link to it or by
+// a mention of its name
+type Ref struct {
+	Path, Name, Rel string
+	Match               // the line, shortened, with the reference highlighted
+	Start, End      int // byte range of the reference in the whole line
+}
+
+// parseLinks finds the markdown and wiki links in content, leaving out
+// code blocks and code spans. Images are not links to notes.
+func parseLinks(content string) []Link {
+	var links []Link
+	fence := ""
+	for i, line := range strings.Split(content, "\n") {
+		if fence, line = skipFence(fence, line); line == "" {
+			continue
+		}
+		code := codeSpans(line)
+		for j := 0; j < len(line); {
+			if s, ok := inSpans(code, j); ok {
+				j = s.End
+				continue
+			}
+			l, end := linkAt(line, j)
+			if end < 0 {
+				j++
+				continue
+			}
+			if l.Dest != "" {
+				l.Line = i
+				links = append(links, l)
+			}
+			j = end
+		}
+	}
+	return links
+}
+
+// skipFence follows fenced code blocks line by line. It returns the fence
+// still open after line, and line itself unless it is code or a fence.
+func skipFence(fence, line string) (string, string) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		if fence != "" {
+			return fence, ""
+		}
+		return fence, line
+	}
+	if fence != "" {
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]+" \t\r") == "" {
+			return "", ""
+		}
+		return fence, ""
+	}
+	for _, c := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, c) {
+			n := len(trimmed) - len(strings.TrimLeft(trimmed, c[:1]))
+			return trimmed[:n], ""
+		}
+	}
+	return "", line
+}
+
+// codeSpans returns the byte ranges of the `code spans` in line
+func codeSpans(line string) []Span {
+	var spans []Span
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		n := len(line[i:]) - len(strings.TrimLeft(line[i:], "`"))
+		closer := strings.Index(line[i+n:], line[i:i+n])
+		if closer < 0 {
+			i += n
+			continue
+		}
+		end := i + n + closer + n
+		spans = append(spans, Span{i, end})
+		i = end
+	}
+	return spans
+}
+
+// inSpans returns the span that contains offset i, if any
+func inSpans(spans []Span, i int) (Span, bool) {
+	for _, s := range spans {
+		if i >= s.Start && i < s.End {
+			return s, true
+		}
+	}
+	return Span{}, false
+}
+
+// linkAt parses a link starting at offset i of line and returns it with
+// the offset after it, or -1 if there is none there. Images come back
+// with no destination.
+func linkAt(line string, i int) (Link, int) {
+	switch {
+	case strings.HasPrefix(line[i:], "[["):
+		closer := strings.Index(line[i+2:], "]]")
+		if closer < 0 {
+			return Link{}, -1
+		}
+		inner := line[i+2 : i+2+closer]
+		if strings.TrimSpace(inner) == "" || strings.ContainsAny(inner, "[]") {
+			return Link{}, -1
+		}
+		target, _, _ := strings.Cut(inner, "|")
+		end := i + closer + 4
+		return Link{Dest: strings.TrimSpace(target), Wiki: true, Start: i, End: end}, end
+	case line[i] == '[' || strings.HasPrefix(line[i:], "!["):
+		open := strings.IndexByte(line[i:], '[') + i
+		label := strings.IndexByte(line[open+1:], ']')
+		if label < 0 {
+			return Link{}, -1
+		}
+		paren := open + 1 + label + 1
+		if paren >= len(line) || line[paren] != '(' {
+			return Link{}, -1
+		}
+		closer := strings.IndexByte(line[paren:], ')')
+		if closer < 0 {
+			return Link{}, -1
+		}
+		end := paren + closer + 1
+		if line[i] == '!' {
+			return Link{}, end
+		}
+		dest := strings.TrimSpace(line[paren+1 : end-1])
+		if strings.HasPrefix(dest, "<") {
+			if j := strings.IndexByte(dest, '>'); j > 0 {
+				dest = dest[1:j]
+			}
+		} else if j := strings.IndexAny(dest, " \t"); j >= 0 {
+			dest = dest[:j] // Drop the "title"
+		}
+		return Link{Dest: dest, Start: i, End: end}, end
+	}
+	return Link{}, -1
+}
+
+// linkName returns the file name a link points at, in lower case and
+// without .md, or "" for a link within its own note. Whatever the link
+// resolves to, it can only be a note of that name, so the index keeps the
+// notes that link to each name.
+func linkName(l Link) string {
+	dest, _, _ := strings.Cut(l.Dest, "#")
+	if !l.Wiki {
+		if unescaped, err := url.PathUnescape(dest); err == nil {
+			dest = unescaped
+		}
+	}
+	dest = strings.ToLower(strings.TrimSpace(dest))
+	if dest == "" {
+		return ""
+	}
+	return strings.TrimSuffix(path.Base(dest), ".md")
+}
+
+// Backlinks returns the lines of other notes that link to the note at
+// path. resolve returns the path a link in the note at from points at, or
+// "" if it points at no note. Only the links to a note of the same name
+// are resolved.
+func (ix *Index) Backlinks(path string, resolve func(from string, l Link) string) []Ref {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	target, ok := ix.docs[path]
+	if !ok {
+		return nil
+	}
+	name := linkName(Link{Dest: target.rel, Wiki: true})
+	var refs []Ref
+	for from := range ix.linkers[name] {
+		if from == path {
+			continue
+		}
+		d := ix.docs[from]
+		var lines []string
+		for _, l := range d.links {
+			if linkName(l) != name || resolve(from, l) != path {
+				continue
+			}
+			if lines == nil {
+				lines = strings.Split(d.content, "\n")
+			}
+			refs = append(refs, newRef(d, lines[l.Line], l.Line, l.Start, l.End))
+		}
+	}
+	return sortRefs(refs)
+}
+
+// Mentions returns the lines of notes other than skip that have phrase in
+// them as plain text, outside links and code. Case and the characters
+// between words may differ from phrase, but the words must be whole.
+func (ix *Index) Mentions(phrase, skip string) []Ref {
+	words := tokenize(phrase)
+	if len(words) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var refs []Ref
+	for path := range ix.postings[words[0].term] {
+		d := ix.docs[path]
+		if path == skip || !hasTerms(d, words) {
+			continue
+		}
+		fence := ""
+		for i, line := range strings.Split(d.content, "\n") {
+			if fence, line = skipFence(fence, line); line == "" {
+				continue
+			}
+			skips := codeSpans(line)
+			for _, l := range d.links {
+				if l.Line == i {
+					skips = append(skips, Span{l.Start, l.End})
+				}
+			}
+			for _, m := range findPhrase(line, words) {
+				if !overlaps(skips, m) {
+					refs = append(refs, newRef(d, line, i, m.Start, m.End))
+				}
+			}
+		}
+	}
+	return sortRefs(refs)
+}
+
+// hasTerms returns true if d has every word somewhere
+func hasTerms(d *doc, words []token) bool {
+	for _, w := range words {
+		if d.terms[w.term] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// findPhrase returns where the words of phrase follow each other in line
+func findPhrase(line string, words []token) []Span {
+	toks := tokenize(line)
+	var found []Span
+	for i := 0; i+len(words) <= len(toks); i++ {
+		ok := true
+		for j, w := range words {
+			if toks[i+j].term != w.term {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			found = append(found, Span{toks[i].start, toks[i+len(words)-1].end})
+			i += len(words) - 1
+		}
+	}
+	return found
+}
+
+// overlaps returns true if s overlaps any of spans
+func overlaps(spans []Span, s Span) bool {
+	for _, o := range spans {
+		if s.Start < o.End && o.Start < s.End {
+			return true
+		}
+	}
+	return false
+}
+
+func newRef(d *doc, line string, n, start, end int) Ref {
+	text, spans := shorten(line, []Span{{start, end}})
+	return Ref{
+		Path:  d.path,
+		Name:  d.name,
+		Rel:   d.rel,
+		Match: Match{Line: n, Text: text, Spans: spans},
+		Start: start,
+		End:   end,
+	}
+}
+
+// sortRefs orders refs by note and line and keeps the first maxRefs
+func sortRefs(refs []Ref) []Ref {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Rel != refs[j].Rel {
+			return refs[i].Rel < refs[j].Rel
+		}
+		return refs[i].Line < refs[j].Line
+	})
+	if len(refs) > maxRefs {
+		refs = refs[:maxRefs]
+	}
+	return refs
+}
@@ -0,0 +1,26 @@
+module gioui.org/x
+
+go 1.23.8
+
+toolchain go1.24.3
+
+require (
+	gioui.org v0.9.0
+	git.sr.ht/~jackmordaunt/go-toast v1.0.0
+	git.wow.st/gmp/jni v0.0.0-20210610011705-34026c7e22d0
+	github.com/andybalholm/stroke v0.0.0-20221221101821-bd29b49d73f0
+	github.com/esiqveland/notify v0.11.0
+	github.com/godbus/dbus/v5 v5.0.6
+	github.com/yuin/goldmark v1.4.13
+	golang.org/x/exp v0.0.0-20250408133849-7e4ce0ab07d0
+	golang.org/x/exp/shiny v0.0.0-20250408133849-7e4ce0ab07d0
+	golang.org/x/image v0.26.0
+	golang.org/x/sys v0.33.0
+	golang.org/x/text v0.24.0
+)
+
+require (
+	gioui.org/shader v1.0.8 // indirect
+	github.com/go-ole/go-ole v1.2.6 // indirect
+	github.com/go-text/typesetting v0.3.0 // indirect
+)
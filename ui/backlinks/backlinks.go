@@ -0,0 +1,282 @@
+package backlinks
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/styledtext"
+
+	"giopad/app"
+	"giopad/links"
+	"giopad/search"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// refClicks holds the buttons of one listed line: the line itself and,
+// for unlinked mentions, [Link it]
+type refClicks struct {
+	open   widget.Clickable
+	linkIt widget.Clickable
+}
+
+// Panel is the side panel for what refers to the open note: the lines of
+// other notes that link to it, then those that name it without a link
+type Panel struct {
+	indexer  *search.Indexer
+	notes    *links.Notes
+	path     string
+	gen      uint64
+	linked   []search.Ref
+	unlinked []search.Ref
+	clicks   []refClicks
+	list     widget.List
+	active   bool
+
+	closeClick widget.Clickable
+
+	onOpen   func(path string, line int)
+	onLinkIt func(m search.Ref)
+}
+
+// New creates a backlinks panel. onOpen runs when a line is clicked, with
+// its 0-based number; onLinkIt when an unlinked mention should become a
+// link.
+func New(onOpen func(path string, line int), onLinkIt func(m search.Ref)) *Panel {
+	p := &Panel{onOpen: onOpen, onLinkIt: onLinkIt}
+	p.list.Axis = layout.Vertical
+	return p
+}
+
+// SetIndexer sets the vault index links and mentions are looked up in
+func (p *Panel) SetIndexer(x *search.Indexer) {
+	p.indexer = x
+	p.path = ""
+}
+
+// Open shows the panel
+func (p *Panel) Open() {
+	p.active = true
+}
+
+// Close hides the panel
+func (p *Panel) Close() {
+	p.active = false
+}
+
+// Active returns true while the panel is shown
+func (p *Panel) Active() bool {
+	return p.active
+}
+
+// run looks the note up again if it, the notes or the index changed since
+// last time
+func (p *Panel) run(path string, notes *links.Notes) {
+	if p.indexer == nil || path == "" {
+		p.path, p.linked, p.unlinked = path, nil, nil
+		return
+	}
+	gen := p.indexer.Index.Generation()
+	if path == p.path && notes == p.notes && gen == p.gen {
+		return
+	}
+	p.path, p.notes, p.gen = path, notes, gen
+	p.linked = notes.Backlinks(p.indexer.Index, path)
+	p.unlinked = notes.Mentions(p.indexer.Index, path)
+	if n := len(p.linked) + len(p.unlinked); len(p.clicks) < n {
+		p.clicks = append(p.clicks, make([]refClicks, n-len(p.clicks))...)
+	}
+}
+
+// ref returns the i-th listed line, backlinks first
+func (p *Panel) ref(i int) (search.Ref, bool) {
+	if i < len(p.linked) {
+		return p.linked[i], false
+	}
+	return p.unlinked[i-len(p.linked)], true
+}
+
+// Layout renders the panel for the note at path, resolving links against
+// notes
+func (p *Panel) Layout(gtx C, th *material.Theme, path string, notes *links.Notes) D {
+	if !p.active {
+		return D{}
+	}
+
+	if p.closeClick.Clicked(gtx) {
+		p.Close()
+		return D{}
+	}
+	p.run(path, notes)
+	for i := range len(p.linked) + len(p.unlinked) {
+		r, mention := p.ref(i)
+		if p.clicks[i].open.Clicked(gtx) && p.onOpen != nil {
+			p.onOpen(r.Path, r.Line)
+		}
+		if mention && p.clicks[i].linkIt.Clicked(gtx) && p.onLinkIt != nil {
+			p.onLinkIt(r)
+		}
+	}
+
+	// Two headings and the lines under each, or a "None" in their place
+	rows := []func(gtx C) D{
+		func(gtx C) D { return layoutSection(gtx, th, "Linked mentions", len(p.linked)) },
+	}
+	addRefs := func(offset, n int) {
+		if n == 0 {
+			rows = append(rows, func(gtx C) D { return layoutNone(gtx, th) })
+		}
+		for i := offset; i < offset+n; i++ {
+			rows = append(rows, func(gtx C) D { return p.layoutRef(gtx, th, i) })
+		}
+	}
+	addRefs(0, len(p.linked))
+	rows = append(rows, func(gtx C) D { return layoutSection(gtx, th, "Unlinked mentions", len(p.unlinked)) })
+	addRefs(len(p.linked), len(p.unlinked))
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return p.layoutHeader(gtx, th)
+		}),
+		layout.Flexed(1, func(gtx C) D {
+			return material.List(th, &p.list).Layout(gtx, len(rows), func(gtx C, i int) D {
+				return rows[i](gtx)
+			})
+		}),
+	)
+}
+
+func (p *Panel) layoutHeader(gtx C, th *material.Theme) D {
+	return layout.Inset{Bottom: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+					layout.Flexed(1, func(gtx C) D {
+						label := material.Body1(th, "Backlinks")
+						label.Color = app.Foreground()
+						label.Font.Weight = font.Bold
+						return label.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						return layoutAction(gtx, th, &p.closeClick, "[Close]", app.Comment())
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx C) D {
+				label := material.Caption(th, p.status())
+				label.Color = app.Comment()
+				label.MaxLines = 1
+				return label.Layout(gtx)
+			}),
+		)
+	})
+}
+
+// status names the note the panel is about
+func (p *Panel) status() string {
+	switch {
+	case p.indexer == nil:
+		return "No vault loaded"
+	case p.path == "":
+		return "No note open"
+	case p.indexer.Busy():
+		return fmt.Sprintf("Indexing… %d notes so far", p.indexer.Index.Len())
+	default:
+		return "To " + p.notes.Title(p.path)
+	}
+}
+
+// layoutSection renders the heading of a list of references
+func layoutSection(gtx C, th *material.Theme, title string, n int) D {
+	return layout.Inset{Top: unit.Dp(8), Bottom: unit.Dp(2)}.Layout(gtx, func(gtx C) D {
+		label := material.Body2(th, fmt.Sprintf("%s (%d)", title, n))
+		label.Color = app.Purple()
+		label.Font.Weight = font.Bold
+		return label.Layout(gtx)
+	})
+}
+
+func layoutNone(gtx C, th *material.Theme) D {
+	return layout.Inset{Top: unit.Dp(4), Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+		label := material.Caption(th, "None")
+		label.Color = app.Comment()
+		return label.Layout(gtx)
+	})
+}
+
+// layoutRef renders the note a line is in with the line below, and for
+// unlinked mentions the button that links them
+func (p *Panel) layoutRef(gtx C, th *material.Theme, i int) D {
+	r, mention := p.ref(i)
+	clicks := &p.clicks[i]
+	return layout.Inset{Top: unit.Dp(6), Bottom: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Flexed(1, func(gtx C) D {
+				return clicks.open.Layout(gtx, func(gtx C) D {
+					return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+						layout.Rigid(func(gtx C) D {
+							label := material.Body2(th, r.Name)
+							label.Color = app.Blue()
+							label.MaxLines = 1
+							return label.Layout(gtx)
+						}),
+						layout.Rigid(func(gtx C) D {
+							return layout.Inset{Top: unit.Dp(2), Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+								return layoutMatch(gtx, th, r.Match)
+							})
+						}),
+					)
+				})
+			}),
+			layout.Rigid(func(gtx C) D {
+				if !mention {
+					return D{}
+				}
+				return layoutAction(gtx, th, &clicks.linkIt, "[Link it]", app.Green())
+			}),
+		)
+	})
+}
+
+// layoutMatch renders a line number and the line with the reference
+// highlighted
+func layoutMatch(gtx C, th *material.Theme, m search.Match) D {
+	size := unit.Sp(12)
+	plain := font.Font{}
+	bold := font.Font{Weight: font.Bold}
+	spans := []styledtext.SpanStyle{
+		{Content: strconv.Itoa(m.Line+1) + ": ", Size: size, Color: app.Comment(), Font: plain},
+	}
+	pos := 0
+	for _, s := range m.Spans {
+		if s.Start > pos {
+			spans = append(spans, styledtext.SpanStyle{Content: m.Text[pos:s.Start], Size: size, Color: app.Foreground(), Font: plain})
+		}
+		spans = append(spans, styledtext.SpanStyle{Content: m.Text[s.Start:s.End], Size: size, Color: app.Yellow(), Font: bold})
+		pos = s.End
+	}
+	if pos < len(m.Text) {
+		spans = append(spans, styledtext.SpanStyle{Content: m.Text[pos:], Size: size, Color: app.Foreground(), Font: plain})
+	}
+	return styledtext.Text(th.Shaper, spans...).Layout(gtx, nil)
+}
+
+// layoutAction renders a bracketed text button
+func layoutAction(gtx C, th *material.Theme, click *widget.Clickable, text string, col color.NRGBA) D {
+	return click.Layout(gtx, func(gtx C) D {
+		return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4), Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+			label := material.Body2(th, text)
+			label.Color = col
+			return label.Layout(gtx)
+		})
+	})
+}
@@ -0,0 +1,362 @@
+package dialog
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/font"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"giopad/app"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// Dialog is an in-app modal: a message and a row of buttons drawn over a
+// scrim that swallows pointer input to the rest of the window. It can also
+// ask for a line of text (ShowPrompt) or offer a list of items (ShowMenu).
+type Dialog struct {
+	title    string
+	message  string
+	buttons  []string
+	onChoice func(choice int)
+	onInput  func(choice int, text string)
+
+	menu      bool // Buttons stacked as a scrollable menu
+	prompt    bool // Text field above the buttons
+	highlight int  // Menu item picked by Enter
+
+	clicks       []widget.Clickable
+	input        widget.Editor
+	menuList     widget.List
+	visible      bool
+	requestFocus bool
+	scrim        int // pointer tag for the scrim
+	card         int // pointer tag for the card
+}
+
+// New creates a hidden Dialog
+func New() *Dialog {
+	d := &Dialog{}
+	d.input.SingleLine = true
+	d.input.Submit = true
+	d.menuList.Axis = layout.Vertical
+	return d
+}
+
+// Show opens the dialog. onChoice receives the index of the pressed button.
+// Enter picks the first button and Escape the last, so put the default
+// action first and Cancel last.
+func (d *Dialog) Show(title, message string, buttons []string, onChoice func(choice int)) {
+	d.open(title, message, buttons)
+	d.onChoice = onChoice
+}
+
+// ShowPrompt opens the dialog with a text field holding text. onInput
+// receives the pressed button and the edited text; Enter picks the first
+// button and Escape the last.
+func (d *Dialog) ShowPrompt(title, message, text string, buttons []string, onInput func(choice int, text string)) {
+	d.open(title, message, buttons)
+	d.prompt = true
+	d.onInput = onInput
+	d.input.SetText(text)
+	d.input.SetCaret(len([]rune(text)), 0)
+}
+
+// ShowMenu opens a list of items. onChoice receives the picked index;
+// Escape or a click outside closes the menu without calling it.
+func (d *Dialog) ShowMenu(title string, items []string, onChoice func(choice int)) {
+	d.open(title, "", items)
+	d.menu = true
+	d.onChoice = onChoice
+	d.menuList.Position = layout.Position{}
+}
+
+func (d *Dialog) open(title, message string, buttons []string) {
+	d.title = title
+	d.message = message
+	d.buttons = buttons
+	d.onChoice = nil
+	d.onInput = nil
+	d.menu = false
+	d.prompt = false
+	d.highlight = 0
+	d.clicks = make([]widget.Clickable, len(buttons))
+	d.visible = true
+	d.requestFocus = true
+}
+
+// Visible returns true while the dialog is waiting for a choice
+func (d *Dialog) Visible() bool {
+	return d.visible
+}
+
+// choose closes the dialog and reports the choice; -1 just closes it
+func (d *Dialog) choose(i int) {
+	d.visible = false
+	if i < 0 {
+		return
+	}
+	if d.onInput != nil {
+		d.onInput(i, d.input.Text())
+	} else if d.onChoice != nil {
+		d.onChoice(i)
+	}
+}
+
+// cancel closes the dialog the way Escape does
+func (d *Dialog) cancel() {
+	if d.menu {
+		d.choose(-1)
+	} else {
+		d.choose(len(d.buttons) - 1)
+	}
+}
+
+// Layout renders the dialog over the whole window, if visible
+func (d *Dialog) Layout(gtx C, th *material.Theme) D {
+	if !d.visible {
+		return D{}
+	}
+
+	// Handle button clicks
+	for i := range d.clicks {
+		if d.clicks[i].Clicked(gtx) {
+			d.choose(i)
+			return D{}
+		}
+	}
+
+	// Handle Enter in the text field
+	if d.prompt {
+		for {
+			ev, ok := d.input.Update(gtx)
+			if !ok {
+				break
+			}
+			if _, ok := ev.(widget.SubmitEvent); ok {
+				d.choose(0)
+				return D{}
+			}
+		}
+	}
+
+	// Handle Enter/Escape and menu navigation
+	for {
+		ev, ok := gtx.Event(
+			key.Filter{Focus: d, Name: key.NameReturn},
+			key.Filter{Focus: d, Name: key.NameEnter},
+			key.Filter{Focus: d, Name: key.NameEscape},
+			key.Filter{Focus: d, Name: key.NameUpArrow},
+			key.Filter{Focus: d, Name: key.NameDownArrow},
+			key.Filter{Name: key.NameEscape},
+		)
+		if !ok {
+			break
+		}
+		e, ok := ev.(key.Event)
+		if !ok || e.State != key.Press || len(d.buttons) == 0 {
+			continue
+		}
+		switch e.Name {
+		case key.NameEscape:
+			d.cancel()
+			return D{}
+		case key.NameUpArrow:
+			if d.highlight > 0 {
+				d.highlight--
+				d.menuList.ScrollTo(d.highlight)
+			}
+		case key.NameDownArrow:
+			if d.highlight < len(d.buttons)-1 {
+				d.highlight++
+				d.menuList.ScrollTo(d.highlight)
+			}
+		default:
+			d.choose(d.highlight)
+			return D{}
+		}
+	}
+
+	// Swallow pointer events aimed at the widgets underneath; a press
+	// outside a menu closes it
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target:  &d.scrim,
+			Kinds:   pointer.Press | pointer.Release | pointer.Drag | pointer.Scroll,
+			ScrollX: pointer.ScrollRange{Min: -1 << 30, Max: 1 << 30},
+			ScrollY: pointer.ScrollRange{Min: -1 << 30, Max: 1 << 30},
+		})
+		if !ok {
+			break
+		}
+		if e, ok := ev.(pointer.Event); ok && e.Kind == pointer.Press && d.menu {
+			d.choose(-1)
+			return D{}
+		}
+	}
+
+	for {
+		if _, ok := gtx.Event(pointer.Filter{Target: &d.card, Kinds: pointer.Press}); !ok {
+			break
+		}
+	}
+
+	size := gtx.Constraints.Max
+	area := clip.Rect(image.Rectangle{Max: size}).Push(gtx.Ops)
+	paint.ColorOp{Color: color.NRGBA{A: 0x80}}.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	event.Op(gtx.Ops, &d.scrim)
+	event.Op(gtx.Ops, d)
+	area.Pop()
+
+	if d.requestFocus {
+		if d.prompt {
+			gtx.Execute(key.FocusCmd{Tag: &d.input})
+		} else {
+			gtx.Execute(key.FocusCmd{Tag: d})
+		}
+		d.requestFocus = false
+	}
+
+	gtx.Constraints.Min = image.Point{}
+	layout.Center.Layout(gtx, func(gtx C) D {
+		if maxW := gtx.Dp(unit.Dp(360)); gtx.Constraints.Max.X > maxW {
+			gtx.Constraints.Max.X = maxW
+		}
+		if d.menu {
+			gtx.Constraints.Max.Y = gtx.Constraints.Max.Y * 3 / 4
+		}
+		return d.layoutCard(gtx, th)
+	})
+	return D{Size: size}
+}
+
+func (d *Dialog) layoutCard(gtx C, th *material.Theme) D {
+	return layout.Stack{}.Layout(gtx,
+		// Card background
+		layout.Expanded(func(gtx C) D {
+			rect := image.Rectangle{Max: gtx.Constraints.Min}
+			paint.FillShape(gtx.Ops, app.Surface(), clip.UniformRRect(rect, gtx.Dp(unit.Dp(6))).Op(gtx.Ops))
+			// Keep presses on the card from reaching the scrim
+			defer clip.Rect(rect).Push(gtx.Ops).Pop()
+			event.Op(gtx.Ops, &d.card)
+			return D{Size: gtx.Constraints.Min}
+		}),
+		// Content
+		layout.Stacked(func(gtx C) D {
+			return layout.UniformInset(unit.Dp(16)).Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					// Title
+					layout.Rigid(func(gtx C) D {
+						label := material.Body1(th, d.title)
+						label.Color = app.Foreground()
+						label.Font.Weight = font.Bold
+						return label.Layout(gtx)
+					}),
+					// Message
+					layout.Rigid(func(gtx C) D {
+						if d.message == "" {
+							return D{}
+						}
+						return layout.Inset{Top: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+							label := material.Body2(th, d.message)
+							label.Color = app.Foreground()
+							return label.Layout(gtx)
+						})
+					}),
+					// Text field
+					layout.Rigid(func(gtx C) D {
+						if !d.prompt {
+							return D{}
+						}
+						return layout.Inset{Top: unit.Dp(12)}.Layout(gtx, func(gtx C) D {
+							ed := material.Editor(th, &d.input, "")
+							ed.Color = app.Foreground()
+							ed.HintColor = app.Comment()
+							ed.TextSize = unit.Sp(14)
+							return ed.Layout(gtx)
+						})
+					}),
+					// Buttons: a menu, or a right-aligned row
+					layout.Rigid(func(gtx C) D {
+						if d.menu {
+							return layout.Inset{Top: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+								return d.layoutMenu(gtx, th)
+							})
+						}
+						return layout.Inset{Top: unit.Dp(16)}.Layout(gtx, func(gtx C) D {
+							return layout.E.Layout(gtx, d.layoutButtons(th))
+						})
+					}),
+				)
+			})
+		}),
+	)
+}
+
+func (d *Dialog) layoutButtons(th *material.Theme) layout.Widget {
+	return func(gtx C) D {
+		children := make([]layout.FlexChild, len(d.buttons))
+		for i := range d.buttons {
+			i := i
+			children[i] = layout.Rigid(func(gtx C) D {
+				return d.clicks[i].Layout(gtx, func(gtx C) D {
+					return layout.Inset{
+						Top:    unit.Dp(6),
+						Bottom: unit.Dp(6),
+						Left:   unit.Dp(12),
+					}.Layout(gtx, func(gtx C) D {
+						label := material.Body2(th, "["+d.buttons[i]+"]")
+						label.Color = app.Comment()
+						if i == 0 {
+							label.Color = app.Accent()
+						}
+						return label.Layout(gtx)
+					})
+				})
+			})
+		}
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx, children...)
+	}
+}
+
+func (d *Dialog) layoutMenu(gtx C, th *material.Theme) D {
+	return material.List(th, &d.menuList).Layout(gtx, len(d.buttons), func(gtx C, i int) D {
+		return d.clicks[i].Layout(gtx, func(gtx C) D {
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx C) D {
+					if i == d.highlight {
+						rect := image.Rectangle{Max: gtx.Constraints.Min}
+						paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(rect).Op())
+					}
+					return D{Size: gtx.Constraints.Min}
+				}),
+				layout.Stacked(func(gtx C) D {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					return layout.Inset{
+						Top:    unit.Dp(6),
+						Bottom: unit.Dp(6),
+						Left:   unit.Dp(8),
+						Right:  unit.Dp(8),
+					}.Layout(gtx, func(gtx C) D {
+						label := material.Body2(th, d.buttons[i])
+						label.Color = app.Foreground()
+						return label.Layout(gtx)
+					})
+				}),
+			)
+		})
+	})
+}
@@ -0,0 +1,167 @@
+package editor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"gioui.org/x/markdown"
+	"gioui.org/x/richtext"
+
+	"github.com/yuin/goldmark/ast"
+
+	"giopad/app"
+	"giopad/internal/syntax"
+)
+
+// codeBlock is a code block of the preview, highlighted for its language,
+// or some lines of a long one
+type codeBlock struct {
+	label string // the language as the info string names it, or ""
+	last  bool   // the last part of its code block
+}
+
+// renderCode renders the code block n of src, coloured if its info string
+// names a language syntax knows. Long code is split into parts of
+// codePartLines lines, tokenized as a whole so strings and comments that
+// span parts stay coloured.
+func renderCode(r *markdown.Renderer, src []byte, n ast.Node) []*block {
+	label := ""
+	if f, ok := n.(*ast.FencedCodeBlock); ok && f.Info != nil {
+		info := string(f.Info.Segment.Value(src))
+		if fields := strings.Fields(info); len(fields) > 0 {
+			label = fields[0]
+		}
+	}
+	var code strings.Builder
+	for i := 0; i < n.Lines().Len(); i++ {
+		seg := n.Lines().At(i)
+		code.Write(seg.Value(src))
+	}
+	toks := syntax.Tokenize(syntax.Lang(label), strings.TrimRight(code.String(), "\n"))
+
+	// Break the tokens into lines, then join the lines of each part
+	lines := [][]syntax.Token{nil}
+	for _, t := range toks {
+		for i, text := range strings.Split(t.Text, "\n") {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			if text != "" {
+				lines[len(lines)-1] = append(lines[len(lines)-1], syntax.Token{Text: text, Kind: t.Kind})
+			}
+		}
+	}
+	var parts []*block
+	for first := 0; first < len(lines); first += codePartLines {
+		b := &block{kind: kindCode, source: string(src), part: len(parts), code: &codeBlock{}}
+		if first == 0 {
+			b.code.label = label
+		} else {
+			b.joined = true
+			if first < n.Lines().Len() {
+				start := lineStart(src, n.Lines().At(first).Start)
+				b.partLine = bytes.Count(src[:start], []byte("\n"))
+				b.partRune = utf8.RuneCount(src[:start])
+			}
+		}
+		for i, line := range lines[first:min(first+codePartLines, len(lines))] {
+			if i > 0 {
+				b.spans = append(b.spans, codeSpan(r, "\n", syntax.Plain))
+			}
+			for _, t := range line {
+				b.spans = append(b.spans, codeSpan(r, t.Text, t.Kind))
+			}
+		}
+		parts = append(parts, b)
+	}
+	parts[len(parts)-1].code.last = true
+	return parts
+}
+
+// codeSpan is a span of code, in a monospace font
+func codeSpan(r *markdown.Renderer, text string, k syntax.Kind) richtext.SpanStyle {
+	return richtext.SpanStyle{
+		Content: text,
+		Font:    r.Config.MonospaceFont,
+		Size:    r.Config.DefaultSize,
+		Color:   tokenColor(k),
+	}
+}
+
+// tokenColor returns the colour of a kind of token in the current theme
+func tokenColor(k syntax.Kind) color.NRGBA {
+	switch k {
+	case syntax.Keyword, syntax.Heading:
+		return app.Yellow()
+	case syntax.Builtin, syntax.Number:
+		return app.Purple()
+	case syntax.String, syntax.Inserted:
+		return app.Green()
+	case syntax.Comment:
+		return app.Comment()
+	case syntax.Function, syntax.Key:
+		return app.Blue()
+	case syntax.Deleted:
+		return app.Red()
+	case syntax.Meta:
+		return app.Cyan()
+	}
+	return app.Foreground()
+}
+
+// layoutCode draws a code block in a tinted box, with its language in the
+// top right corner. The parts of a long block draw as one box.
+func (p *preview) layoutCode(gtx C, th *material.Theme, b *block) D {
+	inset := layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10)}
+	if b.part == 0 {
+		inset.Top = unit.Dp(10)
+	}
+	if b.code.last {
+		inset.Bottom = unit.Dp(10)
+	}
+	m := op.Record(gtx.Ops)
+	gtx.Constraints.Min.X = gtx.Constraints.Max.X
+	dims := inset.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				if b.code.label == "" {
+					return D{}
+				}
+				return layout.E.Layout(gtx, func(gtx C) D {
+					label := material.Caption(th, b.code.label)
+					label.Color = app.Comment()
+					label.MaxLines = 1
+					return label.Layout(gtx)
+				})
+			}),
+			layout.Rigid(func(gtx C) D {
+				gtx.Constraints.Min.X = gtx.Constraints.Max.X
+				return richtext.Text(&b.state, th.Shaper, b.spans...).Layout(gtx)
+			}),
+		)
+	})
+	call := m.Stop()
+
+	tint := app.Selection()
+	tint.A = 0x80
+	rrect := clip.RRect{Rect: image.Rectangle{Max: dims.Size}}
+	radius := gtx.Dp(unit.Dp(4))
+	if b.part == 0 {
+		rrect.NW, rrect.NE = radius, radius
+	}
+	if b.code.last {
+		rrect.SW, rrect.SE = radius, radius
+	}
+	paint.FillShape(gtx.Ops, tint, rrect.Op(gtx.Ops))
+	call.Add(gtx.Ops)
+	return dims
+}
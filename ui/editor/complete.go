@@ -0,0 +1,272 @@
+package editor
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"giopad/app"
+	"giopad/internal/fuzzy"
+	"giopad/links"
+)
+
+// maxCompletions is how many note names the [[ popup offers at once
+const maxCompletions = 8
+
+// completion offers note names while a wiki link is typed, from its [[
+// up to the caret
+type completion struct {
+	start     int // rune offset after the [[, or -1 when closed
+	query     string
+	caret     int // caret the popup was last updated for
+	dismissed int // start of the link Escape closed the popup for
+	names     []string
+	highlight int
+
+	notes *links.Notes // where all came from
+	all   []string
+
+	clicks [maxCompletions]widget.Clickable
+	box    int // pointer tag that keeps presses off the editor underneath
+}
+
+func newCompletion() completion {
+	return completion{start: -1, caret: -1, dismissed: -1}
+}
+
+// open returns true if the popup is showing
+func (c *completion) open() bool {
+	return c.start >= 0 && len(c.names) > 0
+}
+
+// updateCompletion opens, filters or closes the popup for where the caret
+// is now. changed is set if the text changed since the last call.
+func (e *Editor) updateCompletion(changed bool) {
+	c := &e.complete
+	caret, end := e.textEditor.Selection()
+	if caret != end {
+		c.start = -1
+		return
+	}
+	if !changed && caret == c.caret {
+		return
+	}
+	c.caret = caret
+	start, query := wikiQuery(e.textEditor.Text(), caret)
+	if start != c.dismissed {
+		c.dismissed = -1
+	}
+	if start < 0 || start == c.dismissed {
+		c.start = -1
+		return
+	}
+	if e.links != c.notes {
+		c.notes, c.all = e.links, e.links.Names()
+	}
+	c.start, c.query, c.highlight = start, query, 0
+	c.names = matchNames(query, c.all)
+}
+
+// wikiQuery finds the wiki link being typed at caret: the rune offset
+// after its [[ and what follows up to the caret, or -1 if there is none
+func wikiQuery(text string, caret int) (int, string) {
+	before, _ := splitAtRune(text, caret)
+	line := before[strings.LastIndexByte(before, '\n')+1:]
+	i := strings.LastIndex(line, "[[")
+	if i < 0 {
+		return -1, ""
+	}
+	query := line[i+2:]
+	if strings.ContainsAny(query, "[]|#") {
+		return -1, ""
+	}
+	return caret - utf8.RuneCountInString(query), query
+}
+
+// matchNames returns the best few names for query, best first
+func matchNames(query string, names []string) []string {
+	type scored struct {
+		name  string
+		score int
+	}
+	var found []scored
+	for _, name := range names {
+		if score, _, ok := fuzzy.Match(query, name); ok {
+			found = append(found, scored{name, score})
+		}
+	}
+	slices.SortFunc(found, func(a, b scored) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		if len(a.name) != len(b.name) {
+			return len(a.name) - len(b.name)
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	out := make([]string, 0, min(len(found), maxCompletions))
+	for _, f := range found[:min(len(found), maxCompletions)] {
+		out = append(out, f.name)
+	}
+	return out
+}
+
+// handleCompletionKeys takes the keys the popup needs from the text
+// editor while it shows. It runs before the editor sees its events.
+func (e *Editor) handleCompletionKeys(gtx C) {
+	c := &e.complete
+	if !c.open() {
+		return
+	}
+	tag := &e.textEditor
+	for {
+		ev, ok := gtx.Event(
+			key.Filter{Focus: tag, Name: key.NameUpArrow},
+			key.Filter{Focus: tag, Name: key.NameDownArrow},
+			key.Filter{Focus: tag, Name: key.NameReturn},
+			key.Filter{Focus: tag, Name: key.NameEnter},
+			key.Filter{Focus: tag, Name: key.NameTab},
+			key.Filter{Focus: tag, Name: key.NameEscape},
+		)
+		if !ok {
+			break
+		}
+		k, ok := ev.(key.Event)
+		if !ok || k.State != key.Press {
+			continue
+		}
+		switch k.Name {
+		case key.NameUpArrow:
+			c.highlight = (c.highlight + len(c.names) - 1) % len(c.names)
+		case key.NameDownArrow:
+			c.highlight = (c.highlight + 1) % len(c.names)
+		case key.NameEscape:
+			c.dismissed, c.start = c.start, -1
+			return
+		default:
+			c.accept(e, c.highlight)
+			return
+		}
+	}
+}
+
+// accept puts names[i] into the link being typed, closing it with ]] if
+// it is not closed already. An alias or heading after the name stays.
+func (c *completion) accept(e *Editor, i int) {
+	name := c.names[i]
+	text := e.textEditor.Text()
+	_, rest := splitAtRune(text, c.caret)
+	if nl := strings.IndexByte(rest, '\n'); nl >= 0 {
+		rest = rest[:nl]
+	}
+	stop, closed, suffix := 0, false, false
+	if j := strings.Index(rest, "]]"); j >= 0 && !strings.Contains(rest[:j], "[[") {
+		stop, closed = j, true
+		if k := strings.IndexAny(rest[:j], "|#"); k >= 0 {
+			stop, suffix = k, true
+		}
+	}
+	if !closed {
+		name += "]]"
+	}
+	e.textEditor.SetCaret(c.start, c.caret+utf8.RuneCountInString(rest[:stop]))
+	e.textEditor.Insert(name)
+	if closed && !suffix {
+		e.textEditor.MoveCaret(2, 2) // past the ]] that was there
+	}
+	c.start = -1
+	e.updateDirty()
+	e.restyle = true
+}
+
+// splitAtRune splits s before its n-th rune
+func splitAtRune(s string, n int) (string, string) {
+	b := 0
+	for i := 0; i < n && b < len(s); i++ {
+		_, size := utf8.DecodeRuneInString(s[b:])
+		b += size
+	}
+	return s[:b], s[b:]
+}
+
+// layoutCompletion shows the popup under the caret, or over it if there
+// is no room below, on top of everything else in the window
+func (e *Editor) layoutCompletion(gtx C, th *material.Theme) {
+	c := &e.complete
+	for i := range c.names {
+		if c.clicks[i].Clicked(gtx) {
+			c.accept(e, i)
+			return
+		}
+	}
+	if !c.open() {
+		return
+	}
+
+	m := op.Record(gtx.Ops)
+	gtx.Constraints.Min = image.Point{}
+	gtx.Constraints.Max.X = min(gtx.Constraints.Max.X, gtx.Dp(unit.Dp(320)))
+	dims := layout.Stack{}.Layout(gtx,
+		layout.Expanded(func(gtx C) D {
+			rect := image.Rectangle{Max: gtx.Constraints.Min}
+			paint.FillShape(gtx.Ops, app.Surface(), clip.UniformRRect(rect, gtx.Dp(unit.Dp(4))).Op(gtx.Ops))
+			defer clip.Rect(rect).Push(gtx.Ops).Pop()
+			event.Op(gtx.Ops, &c.box)
+			return D{Size: gtx.Constraints.Min}
+		}),
+		layout.Stacked(func(gtx C) D {
+			return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+				children := make([]layout.FlexChild, len(c.names))
+				for i := range c.names {
+					children[i] = layout.Rigid(func(gtx C) D {
+						return c.layoutItem(gtx, th, i)
+					})
+				}
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+			})
+		}),
+	)
+	call := m.Stop()
+
+	pos := e.textEditor.CaretCoords().Round()
+	pos.Y -= e.list.Position.Offset
+	x := min(pos.X, gtx.Constraints.Max.X-dims.Size.X)
+	y := pos.Y + gtx.Dp(unit.Dp(6))
+	if y+dims.Size.Y > gtx.Constraints.Max.Y {
+		y = pos.Y - gtx.Dp(unit.Dp(20)) - dims.Size.Y
+	}
+	m = op.Record(gtx.Ops)
+	op.Offset(image.Pt(max(x, 0), y)).Add(gtx.Ops)
+	call.Add(gtx.Ops)
+	op.Defer(gtx.Ops, m.Stop())
+}
+
+func (c *completion) layoutItem(gtx C, th *material.Theme, i int) D {
+	return c.clicks[i].Layout(gtx, func(gtx C) D {
+		if i == c.highlight {
+			rect := image.Rectangle{Max: image.Pt(gtx.Constraints.Max.X, gtx.Dp(unit.Dp(28)))}
+			paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(rect).Op())
+		}
+		gtx.Constraints.Min.X = gtx.Constraints.Max.X
+		gtx.Constraints.Min.Y = gtx.Dp(unit.Dp(28))
+		return layout.W.Layout(gtx, func(gtx C) D {
+			return layout.Inset{Left: unit.Dp(8), Right: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+				label := material.Body2(th, c.names[i])
+				label.Color = app.Foreground()
+				label.MaxLines = 1
+				return label.Layout(gtx)
+			})
+		})
+	})
+}
@@ -1,17 +1,29 @@
 package editor
 
 import (
+	"bytes"
+	"image"
+	"sort"
+
+	"gioui.org/font"
+	"gioui.org/io/event"
 	"gioui.org/io/key"
+	"gioui.org/io/pointer"
 	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
 	"gioui.org/text"
 	"gioui.org/unit"
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 	"gioui.org/x/markdown"
-	"gioui.org/x/richtext"
+	"github.com/yuin/goldmark/extension"
+	"golang.org/x/image/math/fixed"
 
 	"giopad/app"
 	"giopad/fs"
+	"giopad/links"
 )
 
 type (
@@ -19,111 +31,305 @@ type (
 	D = layout.Dimensions
 )
 
+// Mode is what the editor shows of the open note
+type Mode int
+
+const (
+	View  Mode = iota // rendered markdown
+	Edit              // the markdown source
+	Split             // source and a live preview side by side
+)
+
 // Editor displays markdown content
 type Editor struct {
+	// OnLink runs when a link is clicked, with where it points and
+	// whether that exists
+	OnLink func(t links.Target, ok bool)
+	// Links returns the notes links resolve against; nil is fine
+	Links func() *links.Notes
+	// Invalidate redraws the window, from the goroutine an image loaded
+	// on; nil is fine
+	Invalidate func()
+
+	fsys         fs.VaultFS
+	root         string // vault root, or "" if none
 	currentPath  string
 	savedContent []byte // Content as saved on disk
+	dirty        bool   // the buffer differs from savedContent; see updateDirty
+	disk         diskState
 	renderer     *markdown.Renderer
-	spans        []richtext.SpanStyle
-	textState    richtext.InteractiveText
+	preview      *preview     // rendered markdown, for view and split mode
+	links        *links.Notes // what the preview was rendered against
 	list         layout.List
 
 	// Edit mode
-	editMode     bool
+	mode         Mode
 	textEditor   widget.Editor
 	requestFocus bool
+	revealCaret  bool // scroll the caret into view after the next layout
+
+	highlight highlighter
+	restyle   bool // the highlights are behind the text
+	complete  completion
+
+	// Split mode
+	srcHeight int       // height of the source at the last layout
+	scrolling event.Tag // pane under the pointer, which drives scroll sync
+	srcPane   int       // pointer tags for the two panes
+	prevPane  int
+	regions   []widget.Region
+
+	// External change conflict: the file changed on disk while dirty
+	conflict    bool
+	diskContent []byte
+	reloadClick widget.Clickable
+	keepClick   widget.Clickable
 }
 
 // New creates a new Editor
 func New() *Editor {
-	r := markdown.NewRenderer()
+	r := markdown.NewRenderer(links.WikiLinks, extension.TaskList)
 	// Configure colors
 	r.Config.DefaultColor = app.Foreground()
 	r.Config.InteractiveColor = app.Blue()
 	r.Config.DefaultSize = unit.Sp(14)
+	r.Config.MonospaceFont = font.Font{Typeface: "monospace"}
 	r.Config.H1Size = unit.Sp(28)
 	r.Config.H2Size = unit.Sp(24)
 	r.Config.H3Size = unit.Sp(20)
 	r.Config.H4Size = unit.Sp(16)
 
 	e := &Editor{
+		fsys:     fs.OSFS{},
 		renderer: r,
 		list:     layout.List{Axis: layout.Vertical},
+		complete: newCompletion(),
 	}
+	r.Config.LinkColor = e.linkColor
+	e.preview = newPreview(e.loadImage, e.invalidate)
 	e.textEditor.SingleLine = false
 	e.textEditor.Submit = false
 	return e
 }
 
+// SetFS sets the filesystem files are loaded from and saved to, and the
+// root of the vault images are looked up in
+func (e *Editor) SetFS(vfs fs.VaultFS, root string) {
+	e.fsys = vfs
+	e.root = root
+}
+
 // LoadFile loads and parses a markdown file
 func (e *Editor) LoadFile(path string) error {
 	if path == e.currentPath {
 		return nil // Already loaded
 	}
 
-	content, err := fs.ReadFile(path)
+	content, err := e.fsys.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
 	e.currentPath = path
 	e.savedContent = content
-	e.editMode = false
+	e.recordDisk(content)
+	e.mode = View
+	e.conflict = false
+	e.diskContent = nil
 
 	// Set editor content
 	e.textEditor.SetText(string(content))
+	e.updateDirty()
+	e.restyle = true
+	e.complete = newCompletion()
+
+	// Parse markdown into the preview's blocks
+	e.preview = newPreview(e.loadImage, e.invalidate)
+	e.preview.render(e.renderer, content)
+	return nil
+}
+
+// Moved follows the open file to its new path after a rename or move
+func (e *Editor) Moved(newPath string) {
+	if e.currentPath == "" {
+		return
+	}
+	e.currentPath = newPath
+}
 
-	// Parse markdown to richtext spans
-	spans, err := e.renderer.Render(content)
+// Close drops the open file, e.g. after it was deleted
+func (e *Editor) Close() {
+	e.currentPath = ""
+	e.savedContent = nil
+	e.disk = diskState{}
+	e.preview = newPreview(e.loadImage, e.invalidate)
+	e.mode = View
+	e.conflict = false
+	e.diskContent = nil
+	e.textEditor.SetText("")
+	e.updateDirty()
+	e.restyle = true
+}
+
+// CheckDisk re-reads the open file after an external change. A clean
+// buffer is reloaded in place; a dirty one raises a conflict prompt.
+func (e *Editor) CheckDisk() error {
+	if e.currentPath == "" {
+		return nil
+	}
+	content, err := e.fsys.ReadFile(e.currentPath)
 	if err != nil {
 		return err
 	}
-	e.spans = spans
-
+	if bytes.Equal(content, e.savedContent) {
+		e.recordDisk(content)
+		return nil // Our own save, or a no-op write
+	}
+	if !e.IsDirty() {
+		e.reload(content)
+		e.recordDisk(content)
+		return nil
+	}
+	e.conflict = true
+	e.diskContent = content
 	return nil
 }
 
-// ToggleEdit switches between view and edit mode
+// HasConflict returns true if the file changed on disk under unsaved edits
+func (e *Editor) HasConflict() bool {
+	return e.conflict
+}
+
+// reload replaces the buffer with content from disk, keeping the caret
+func (e *Editor) reload(content []byte) {
+	start, end := e.textEditor.Selection()
+	e.savedContent = content
+	e.textEditor.SetText(string(content))
+	e.updateDirty()
+	e.textEditor.SetCaret(start, end)
+	e.restyle = true
+	e.preview.render(e.renderer, content)
+	e.conflict = false
+	e.diskContent = nil
+}
+
+// ToggleEdit switches between view and edit mode. From split mode it
+// goes back to view mode.
 func (e *Editor) ToggleEdit() {
+	if e.mode == View {
+		e.SetMode(Edit)
+	} else {
+		e.SetMode(View)
+	}
+}
+
+// ToggleSplit switches the live preview beside the source on and off
+func (e *Editor) ToggleSplit() {
+	if e.mode == Split {
+		e.SetMode(Edit)
+	} else {
+		e.SetMode(Split)
+	}
+}
+
+// Mode returns what the editor shows
+func (e *Editor) Mode() Mode {
+	return e.mode
+}
+
+// SetMode switches to view, edit or split mode
+func (e *Editor) SetMode(m Mode) {
+	if e.currentPath == "" || m == e.mode {
+		return
+	}
+	if e.mode == View {
+		// Entering edit mode - request focus, showing the caret if it
+		// was left somewhere down the note
+		e.requestFocus = true
+		if start, _ := e.textEditor.Selection(); start > 0 {
+			e.revealCaret = true
+		}
+	}
+	if m != Edit {
+		// Re-render markdown from current text
+		e.preview.render(e.renderer, []byte(e.textEditor.Text()))
+		e.scrolling = &e.srcPane
+	}
+	e.mode = m
+}
+
+// GoToLine switches to edit mode with the caret at the start of line
+// (0-based) and scrolls it into view
+func (e *Editor) GoToLine(line int) {
 	if e.currentPath == "" {
 		return
 	}
+	if e.mode == View {
+		e.SetMode(Edit)
+	}
+	pos := e.lineOffset(line)
+	e.textEditor.SetCaret(pos, pos)
+	e.requestFocus = true
+	e.revealCaret = true
+}
 
-	if e.editMode {
-		// Leaving edit mode - re-render markdown from current text
-		currentText := []byte(e.textEditor.Text())
-		if spans, err := e.renderer.Render(currentText); err == nil {
-			e.spans = spans
+// lineOffset returns the rune offset of the start of line (0-based)
+func (e *Editor) lineOffset(line int) int {
+	pos := 0
+	for _, r := range e.textEditor.Text() {
+		if line == 0 {
+			break
 		}
-	} else {
-		// Entering edit mode - request focus
-		e.textEditor.SetCaret(0, 0)
-		e.requestFocus = true
+		if r == '\n' {
+			line--
+		}
+		pos++
 	}
-	e.editMode = !e.editMode
+	return pos
 }
 
-// Save writes content to disk
-func (e *Editor) Save() error {
-	if e.currentPath == "" || !e.IsDirty() {
-		return nil
+// Position returns the caret and scroll offsets in the open note
+func (e *Editor) Position() app.Position {
+	caret, _ := e.textEditor.Selection()
+	p := app.Position{Caret: caret, Scroll: e.list.Position.Offset}
+	if top := e.preview.list.Position.First; top < len(e.preview.blocks) {
+		p.Line = e.preview.blocks[top].line
 	}
-	content := []byte(e.textEditor.Text())
-	err := fs.WriteFile(e.currentPath, content)
-	if err == nil {
-		e.savedContent = content
+	return p
+}
+
+// SetPosition restores a position saved with Position, e.g. right after
+// LoadFile. The zero Position is the top of the note.
+func (e *Editor) SetPosition(p app.Position) {
+	caret := min(max(p.Caret, 0), e.textEditor.Len())
+	e.textEditor.SetCaret(caret, caret)
+	e.list.Position = layout.Position{Offset: max(p.Scroll, 0)}
+	e.preview.list.Position = layout.Position{First: e.preview.find(p.Line)}
+}
+
+// Revert throws away unsaved edits
+func (e *Editor) Revert() {
+	if e.currentPath == "" {
+		return
 	}
-	return err
+	e.reload(e.savedContent)
 }
 
 // IsDirty returns true if there are unsaved changes
 func (e *Editor) IsDirty() bool {
-	return e.textEditor.Text() != string(e.savedContent)
+	return e.dirty
 }
 
-// IsEditMode returns true if in edit mode
+// updateDirty works out again whether there are unsaved changes. Call it
+// whenever the buffer or savedContent changes, so IsDirty need not compare
+// them on every frame.
+func (e *Editor) updateDirty() {
+	e.dirty = e.textEditor.Text() != string(e.savedContent)
+}
+
+// IsEditMode returns true if the source is shown, alone or split
 func (e *Editor) IsEditMode() bool {
-	return e.editMode
+	return e.mode != View
 }
 
 // Layout renders the markdown content
@@ -134,42 +340,344 @@ func (e *Editor) Layout(gtx C, th *material.Theme) D {
 		return layout.Center.Layout(gtx, label.Layout)
 	}
 
-	return layout.Inset{
+	// Resolve the conflict prompt
+	if e.reloadClick.Clicked(gtx) {
+		e.reload(e.diskContent)
+	}
+	if e.keepClick.Clicked(gtx) {
+		// Treat the disk version as saved so the next save replaces it
+		e.savedContent = e.diskContent
+		e.updateDirty()
+		e.recordDisk(e.diskContent)
+		e.conflict = false
+		e.diskContent = nil
+	}
+
+	if e.conflict {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return e.layoutConflict(gtx, th)
+			}),
+			layout.Flexed(1, func(gtx C) D {
+				return e.layoutContent(gtx, th)
+			}),
+		)
+	}
+	return e.layoutContent(gtx, th)
+}
+
+// layoutConflict renders the banner shown when the file changed on disk
+func (e *Editor) layoutConflict(gtx C, th *material.Theme) D {
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(func(gtx C) D {
+			rect := image.Rectangle{Max: gtx.Constraints.Min}
+			paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(rect).Op())
+			return D{Size: gtx.Constraints.Min}
+		}),
+		layout.Stacked(func(gtx C) D {
+			return layout.Inset{
+				Top:    unit.Dp(6),
+				Bottom: unit.Dp(6),
+				Left:   unit.Dp(24),
+				Right:  unit.Dp(24),
+			}.Layout(gtx, func(gtx C) D {
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+					layout.Flexed(1, func(gtx C) D {
+						label := material.Body2(th, "File changed on disk")
+						label.Color = app.Yellow()
+						return label.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						return e.layoutAction(gtx, th, &e.reloadClick, "[Reload]")
+					}),
+					layout.Rigid(func(gtx C) D {
+						return e.layoutAction(gtx, th, &e.keepClick, "[Keep mine]")
+					}),
+				)
+			})
+		}),
+	)
+}
+
+// layoutAction renders a bracketed text button
+func (e *Editor) layoutAction(gtx C, th *material.Theme, click *widget.Clickable, text string) D {
+	return click.Layout(gtx, func(gtx C) D {
+		return layout.Inset{Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+			label := material.Body2(th, text)
+			label.Color = app.Foreground()
+			return label.Layout(gtx)
+		})
+	})
+}
+
+// layoutContent renders the editor or the rendered markdown, with the
+// target of the link under the pointer along the bottom
+func (e *Editor) layoutContent(gtx C, th *material.Theme) D {
+	// Links change colour when the notes they point at come and go
+	if e.Links != nil {
+		if n := e.Links(); n != e.links {
+			e.links = n
+			if e.mode != Edit {
+				e.preview.forget()
+				e.preview.render(e.renderer, []byte(e.textEditor.Text()))
+			}
+		}
+	}
+
+	dims := layout.Inset{
 		Top:    unit.Dp(16),
 		Left:   unit.Dp(24),
 		Right:  unit.Dp(24),
 		Bottom: unit.Dp(16),
 	}.Layout(gtx, func(gtx C) D {
-		if e.editMode {
-			// Request focus if needed
-			if e.requestFocus {
-				gtx.Execute(key.FocusCmd{Tag: &e.textEditor})
-				e.requestFocus = false
-			}
-
-			// Edit mode - raw text editor
-			ed := material.Editor(th, &e.textEditor, "")
-			ed.Color = app.Foreground()
-			ed.HintColor = app.Comment()
-			ed.TextSize = unit.Sp(14)
-			ed.LineHeight = unit.Sp(20)
-			ed.Editor.Alignment = text.Start
-			return e.list.Layout(gtx, 1, func(gtx C, _ int) D {
-				return ed.Layout(gtx)
-			})
+		switch e.mode {
+		case Edit:
+			return e.layoutSource(gtx, th)
+		case Split:
+			return e.layoutSplit(gtx, th)
 		}
 
 		// View mode - rendered markdown
-		if len(e.spans) == 0 {
+		if e.preview.empty() {
 			label := material.Body1(th, "(empty file)")
 			label.Color = app.Comment()
 			return layout.Center.Layout(gtx, label.Layout)
 		}
+		dims := e.preview.layout(gtx, th)
+		e.preview.clicked = -1 // Only the split moves the caret
+		return dims
+	})
 
-		return e.list.Layout(gtx, 1, func(gtx C, _ int) D {
-			return richtext.Text(&e.textState, th.Shaper, e.spans...).Layout(gtx)
-		})
+	if at := e.preview.toggled; at >= 0 {
+		e.preview.toggled = -1
+		e.toggleTask(at)
+	}
+	if dest := e.preview.followed; dest != "" {
+		e.preview.followed = ""
+		e.followLink(dest)
+	}
+	if e.mode != Edit && e.preview.hover != "" {
+		e.layoutHover(gtx, th, e.preview.hover)
+	}
+	return dims
+}
+
+// layoutSource renders the raw text editor
+func (e *Editor) layoutSource(gtx C, th *material.Theme) D {
+	// Request focus if needed
+	if e.requestFocus {
+		gtx.Execute(key.FocusCmd{Tag: &e.textEditor})
+		e.requestFocus = false
+	}
+
+	e.handleCompletionKeys(gtx)
+	changed := false
+	for {
+		ev, ok := e.textEditor.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := ev.(widget.ChangeEvent); ok {
+			changed = true
+			e.updateDirty()
+			e.restyle = true
+			if e.mode == Split {
+				e.preview.due = gtx.Now.Add(previewDelay)
+			}
+		}
+	}
+	if e.restyle {
+		e.restyle = false
+		e.highlight.update(e.textEditor.Text())
+	}
+	if gtx.Focused(&e.textEditor) {
+		e.updateCompletion(changed)
+	} else {
+		e.complete.start = -1
+	}
+
+	ed := material.Editor(th, &e.textEditor, "")
+	ed.Color = app.Foreground()
+	ed.HintColor = app.Comment()
+	ed.TextSize = unit.Sp(14)
+	ed.LineHeight = unit.Sp(20)
+	ed.Editor.Alignment = text.Start
+	// Paint highlights over about a screen either side of what shows, in
+	// case the list scrolls this frame
+	top, height := e.list.Position.Offset, gtx.Constraints.Max.Y
+	dims := e.list.Layout(gtx, 1, func(gtx C, _ int) D {
+		d := ed.Layout(gtx)
+		e.srcHeight = d.Size.Y
+		params := text.Parameters{
+			Font:       ed.Font,
+			PxPerEm:    fixed.I(gtx.Sp(ed.TextSize)),
+			LineHeight: fixed.I(gtx.Sp(ed.LineHeight)),
+			Alignment:  ed.Editor.Alignment,
+			MinWidth:   gtx.Constraints.Min.X,
+			MaxWidth:   gtx.Constraints.Max.X,
+			Locale:     gtx.Locale,
+		}
+		first, last := e.highlight.visible(top-height, top+2*height, e.lineY)
+		e.highlight.paint(gtx.Ops, th.Shaper, params, first, last, e.lineY)
+		return d
+	})
+	if e.revealCaret {
+		// The list scrolls, not the editor, so move the list to
+		// put the caret a third of the way down
+		e.revealCaret = false
+		y := int(e.textEditor.CaretCoords().Y) - gtx.Constraints.Max.Y/3
+		e.list.Position.First = 0
+		e.list.Position.Offset = max(y, 0)
+		gtx.Execute(op.InvalidateCmd{})
+	}
+	e.layoutCompletion(gtx, th)
+	return dims
+}
+
+// layoutSplit renders the source beside its live preview, or above it on
+// narrow windows, and keeps the two scrolled to the same place
+func (e *Editor) layoutSplit(gtx C, th *material.Theme) D {
+	// Re-render once typing pauses
+	rendered := false
+	if due := e.preview.due; !due.IsZero() && !gtx.Now.Before(due) {
+		e.preview.render(e.renderer, []byte(e.textEditor.Text()))
+		rendered = true
+	}
+
+	// The pane under the pointer is the one being scrolled
+	for _, pane := range []*int{&e.srcPane, &e.prevPane} {
+		for {
+			ev, ok := gtx.Event(pointer.Filter{Target: pane, Kinds: pointer.Enter | pointer.Move})
+			if !ok {
+				break
+			}
+			if _, ok := ev.(pointer.Event); ok {
+				e.scrolling = pane
+			}
+		}
+	}
+
+	srcPos, prevPos := e.list.Position, e.preview.list.Position
+	axis, gap := layout.Horizontal, unit.Dp(24)
+	if gtx.Constraints.Max.X < gtx.Dp(unit.Dp(600)) {
+		axis, gap = layout.Vertical, unit.Dp(16)
+	}
+	dims := layout.Flex{Axis: axis}.Layout(gtx,
+		layout.Flexed(0.5, func(gtx C) D {
+			return e.layoutPane(gtx, &e.srcPane, func(gtx C) D {
+				return e.layoutSource(gtx, th)
+			})
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layoutDivider(gtx, axis, gap)
+		}),
+		layout.Flexed(0.5, func(gtx C) D {
+			return e.layoutPane(gtx, &e.prevPane, func(gtx C) D {
+				return e.preview.layout(gtx, th)
+			})
+		}),
+	)
+
+	if line := e.preview.clicked; line >= 0 {
+		e.preview.clicked = -1
+		pos := e.lineOffset(line)
+		e.textEditor.SetCaret(pos, pos)
+		e.requestFocus = true
+		gtx.Execute(op.InvalidateCmd{})
+	}
+	if due := e.preview.due; !due.IsZero() {
+		gtx.Execute(op.InvalidateCmd{At: due})
+	}
+	switch {
+	case e.scrolling == &e.srcPane && (rendered || e.list.Position != srcPos):
+		e.syncPreview()
+	case e.scrolling == &e.prevPane && e.preview.list.Position != prevPos:
+		e.syncSource()
+	}
+	return dims
+}
+
+// layoutPane renders one side of the split with a pointer tag over all of
+// it, beneath the pane's own handlers
+func (e *Editor) layoutPane(gtx C, tag event.Tag, w layout.Widget) D {
+	m := op.Record(gtx.Ops)
+	dims := w(gtx)
+	call := m.Stop()
+	defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, tag)
+	call.Add(gtx.Ops)
+	return dims
+}
+
+// layoutDivider renders the rule between the panes of the split
+func layoutDivider(gtx C, axis layout.Axis, gap unit.Dp) D {
+	size := image.Pt(gtx.Dp(gap), gtx.Constraints.Max.Y)
+	line := image.Rect(size.X/2, 0, size.X/2+1, size.Y)
+	if axis == layout.Vertical {
+		size = image.Pt(gtx.Constraints.Max.X, gtx.Dp(gap))
+		line = image.Rect(0, size.Y/2, size.X, size.Y/2+1)
+	}
+	paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(line).Op())
+	return D{Size: size}
+}
+
+// lineY returns the top of the source line starting at rune offset off,
+// in pixels from the top of the source
+func (e *Editor) lineY(off int) int {
+	if off >= e.textEditor.Len() {
+		return e.srcHeight
+	}
+	e.regions = e.textEditor.Regions(off, off, e.regions[:0])
+	if len(e.regions) == 0 {
+		return e.srcHeight
+	}
+	return e.regions[0].Bounds.Min.Y
+}
+
+// blockSpan returns where block i of the preview starts and ends in the
+// source, in pixels
+func (e *Editor) blockSpan(i int) (top, bottom int) {
+	blocks := e.preview.blocks
+	top, bottom = e.lineY(blocks[i].offset), e.srcHeight
+	if i+1 < len(blocks) {
+		bottom = e.lineY(blocks[i+1].offset)
+	}
+	return top, max(bottom, top)
+}
+
+// syncPreview scrolls the preview to the block at the top of the source,
+// as far into it as the source is into its lines
+func (e *Editor) syncPreview() {
+	blocks := e.preview.blocks
+	if len(blocks) == 0 {
+		return
+	}
+	y := e.list.Position.Offset
+	i := sort.Search(len(blocks)-1, func(i int) bool {
+		return e.lineY(blocks[i+1].offset) > y
 	})
+	top, bottom := e.blockSpan(i)
+	offset := 0
+	if bottom > top {
+		offset = (y - top) * blocks[i].height / (bottom - top)
+	}
+	e.preview.list.Position = layout.Position{First: i, Offset: max(offset, 0)}
+}
+
+// syncSource scrolls the source to the lines of the block at the top of
+// the preview
+func (e *Editor) syncSource() {
+	blocks := e.preview.blocks
+	pos := e.preview.list.Position
+	if pos.First >= len(blocks) {
+		return
+	}
+	top, bottom := e.blockSpan(pos.First)
+	y := top
+	if h := blocks[pos.First].height; h > 0 {
+		y += min(pos.Offset, h) * (bottom - top) / h
+	}
+	e.list.Position = layout.Position{Offset: max(y, 0)}
 }
 
 // CurrentPath returns the currently loaded file path
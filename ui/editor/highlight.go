@@ -0,0 +1,505 @@
+package editor
+
+import (
+	"image/color"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+
+	"giopad/app"
+)
+
+// style is how a span of markdown source is highlighted
+type style uint8
+
+const (
+	plain    style = iota
+	heading        // # Heading
+	emphasis       // *em*, **strong**, _em_
+	code           // `code` and the lines of fenced blocks
+	meta           // code fences and front matter
+	marker         // list, quote and task markers, thematic breaks
+	link           // [text] of links and images
+	url            // (destination) of links, <autolinks> and bare URLs
+)
+
+// color returns the colour of s in the current theme
+func (s style) color() color.NRGBA {
+	switch s {
+	case heading:
+		return app.Yellow()
+	case emphasis:
+		return app.Purple()
+	case code:
+		return app.Green()
+	case meta:
+		return app.Comment()
+	case marker:
+		return app.Red()
+	case link:
+		return app.Blue()
+	case url:
+		return app.Cyan()
+	}
+	return app.Foreground()
+}
+
+// span is a styled run of one source line, in runes
+type span struct {
+	start, end int
+	style      style
+}
+
+// lineState is what a line inherits from the lines above it
+type lineState struct {
+	fence       string // opening fence while inside a fenced code block
+	frontMatter bool   // inside front matter at the top of the note
+}
+
+// hlLine is one tokenized source line
+type hlLine struct {
+	text   string
+	runes  int
+	offset int // rune offset of the line in the source
+	in     lineState
+	out    lineState
+	spans  []span
+}
+
+// highlighter keeps the spans of every source line. After an edit only the
+// lines that changed, and the lines below whose state they changed, are
+// tokenized again.
+type highlighter struct {
+	lines []hlLine
+}
+
+// update brings the spans in line with src
+func (h *highlighter) update(src string) {
+	texts := strings.Split(src, "\n")
+	old := h.lines
+
+	// Lines before the first change and after the last one keep their
+	// spans, as long as they start in the same state
+	p := 0
+	for p < len(old) && p < len(texts) && old[p].text == texts[p] {
+		p++
+	}
+	s := 0
+	for s < len(old)-p && s < len(texts)-p && old[len(old)-1-s].text == texts[len(texts)-1-s] {
+		s++
+	}
+	lines := make([]hlLine, len(texts))
+	copy(lines, old[:p])
+	copy(lines[len(texts)-s:], old[len(old)-s:])
+
+	var state lineState
+	if p > 0 {
+		state = lines[p-1].out
+	}
+	for i := p; i < len(texts); i++ {
+		if i >= len(texts)-s && lines[i].in == state {
+			break
+		}
+		spans, out := tokenize(texts[i], state, i == 0)
+		lines[i] = hlLine{text: texts[i], runes: utf8.RuneCountInString(texts[i]), in: state, out: out, spans: spans}
+		state = out
+	}
+
+	offset := 0
+	for i := range lines {
+		lines[i].offset = offset
+		offset += lines[i].runes + 1
+	}
+	h.lines = lines
+}
+
+// visible returns the range of lines that overlap [top, bottom), given
+// where each line starts in pixels
+func (h *highlighter) visible(top, bottom int, lineY func(offset int) int) (first, last int) {
+	first = sort.Search(len(h.lines), func(i int) bool {
+		return lineY(h.lines[i].offset) > top
+	})
+	first = max(first-1, 0)
+	last = first + sort.Search(len(h.lines)-first, func(i int) bool {
+		return lineY(h.lines[first+i].offset) >= bottom
+	})
+	return first, last
+}
+
+// paint draws the styled spans of lines [first, last) over the plain text
+// the editor painted. Each line is shaped with the editor's parameters, so
+// the glyphs land exactly on the editor's; lineY anchors them vertically.
+func (h *highlighter) paint(ops *op.Ops, shaper *text.Shaper, params text.Parameters, first, last int, lineY func(offset int) int) {
+	var glyphs []text.Glyph
+	for _, l := range h.lines[first:last] {
+		if len(l.spans) == 0 {
+			continue
+		}
+		shaper.LayoutString(params, l.text)
+		glyphs = glyphs[:0]
+		for {
+			g, ok := shaper.NextGlyph()
+			if !ok {
+				break
+			}
+			glyphs = append(glyphs, g)
+		}
+		if len(glyphs) == 0 {
+			continue
+		}
+		dy := lineY(l.offset) - (int(glyphs[0].Y) - glyphs[0].Ascent.Ceil())
+
+		// Paint runs of glyphs on the same row and of the same style
+		pos, k, start := 0, 0, 0
+		styleAt := func(r int) style {
+			for k < len(l.spans) && l.spans[k].end <= r {
+				k++
+			}
+			if k < len(l.spans) && l.spans[k].start <= r {
+				return l.spans[k].style
+			}
+			return plain
+		}
+		runStyle := styleAt(0)
+		flush := func(end int) {
+			run := glyphs[start:end]
+			start = end
+			if len(run) == 0 || runStyle == plain {
+				return
+			}
+			off := f32.Pt(float32(run[0].X)/64, float32(int(run[0].Y)+dy))
+			t := op.Affine(f32.AffineId().Offset(off)).Push(ops)
+			outline := clip.Outline{Path: shaper.Shape(run)}.Op().Push(ops)
+			paint.ColorOp{Color: runStyle.color()}.Add(ops)
+			paint.PaintOp{}.Add(ops)
+			outline.Pop()
+			t.Pop()
+		}
+		for i, g := range glyphs {
+			if st := styleAt(pos); st != runStyle || g.Y != glyphs[start].Y {
+				flush(i)
+				runStyle = st
+			}
+			pos += int(g.Runes)
+		}
+		flush(len(glyphs))
+	}
+}
+
+// tokenize splits one source line into styled spans. in is the state the
+// line starts in; first is set for the first line of the note.
+func tokenize(line string, in lineState, first bool) ([]span, lineState) {
+	r := []rune(line)
+	out := in
+	whole := func(s style) []span {
+		if len(r) == 0 {
+			return nil
+		}
+		return []span{{0, len(r), s}}
+	}
+	switch {
+	case in.frontMatter:
+		if t := strings.TrimSpace(line); t == "---" || t == "..." {
+			out.frontMatter = false
+		}
+		return whole(meta), out
+	case first && strings.TrimRight(line, " \t") == "---":
+		out.frontMatter = true
+		return whole(meta), out
+	case in.fence != "":
+		if closesFence(line, in.fence) {
+			out.fence = ""
+			return whole(meta), out
+		}
+		return whole(code), out
+	}
+	if fence := opensFence(line); fence != "" {
+		out.fence = fence
+		return whole(meta), out
+	}
+	if isBreak(line) {
+		return whole(marker), out
+	}
+
+	// Block markers: quotes, then a list item, then its task box
+	var spans []span
+	i := skipSpaces(r, 0)
+	for i < len(r) && r[i] == '>' {
+		spans = append(spans, span{i, i + 1, marker})
+		i = skipSpaces(r, i+1)
+	}
+	if n := listMarker(r[i:]); n > 0 {
+		spans = append(spans, span{i, i + n, marker})
+		i = skipSpaces(r, i+n)
+		if rest := string(r[i:min(i+3, len(r))]); rest == "[ ]" || rest == "[x]" || rest == "[X]" {
+			spans = append(spans, span{i, i + 3, marker})
+			i += 3
+		}
+	}
+	if isHeading(r[i:]) {
+		return append(spans, span{i, len(r), heading}), out
+	}
+	return inline(r, i, spans), out
+}
+
+// inline appends the spans of inline markup in r from i on
+func inline(r []rune, i int, spans []span) []span {
+	for i < len(r) {
+		switch c := r[i]; {
+		case c == '\\':
+			i += 2
+		case c == '`':
+			n := runLen(r, i, '`')
+			if j := findRun(r, i+n, '`', n); j >= 0 {
+				spans = append(spans, span{i, j + n, code})
+				i = j + n
+			} else {
+				i += n
+			}
+		case c == '*' || c == '_':
+			n := runLen(r, i, c)
+			if j := closeEmphasis(r, i, n); j >= 0 {
+				spans = append(spans, span{i, j + n, emphasis})
+				i = j + n
+			} else {
+				i += n
+			}
+		case c == '[' || c == '!' && i+1 < len(r) && r[i+1] == '[':
+			if end := closeWiki(r, i); end > 0 {
+				spans = append(spans, span{i, end, link})
+				i = end
+				continue
+			}
+			start := i
+			if c == '!' {
+				i++
+			}
+			end := closeLink(r, i)
+			if end < 0 {
+				i++
+				continue
+			}
+			spans = append(spans, span{start, end, link})
+			i = end
+			if dest := closeDest(r, i); dest > 0 {
+				spans = append(spans, span{i, dest, url})
+				i = dest
+			}
+		case c == '<':
+			j := i + 1
+			for j < len(r) && r[j] != '>' && !unicode.IsSpace(r[j]) {
+				j++
+			}
+			if body := string(r[i+1 : j]); j < len(r) && r[j] == '>' && (strings.Contains(body, "://") || strings.Contains(body, "@")) {
+				spans = append(spans, span{i, j + 1, url})
+				i = j + 1
+			} else {
+				i++
+			}
+		case c == 'h' && (i == 0 || !isWord(r[i-1])) && (hasPrefix(r[i:], "http://") || hasPrefix(r[i:], "https://")):
+			j := i
+			for j < len(r) && !unicode.IsSpace(r[j]) {
+				j++
+			}
+			spans = append(spans, span{i, j, url})
+			i = j
+		default:
+			i++
+		}
+	}
+	return spans
+}
+
+// closeEmphasis returns where the delimiter run of n c's at i closes on
+// the same line, or -1
+func closeEmphasis(r []rune, i, n int) int {
+	c := r[i]
+	if i+n >= len(r) || unicode.IsSpace(r[i+n]) || c == '_' && i > 0 && isWord(r[i-1]) {
+		return -1
+	}
+	for j := i + n + 1; j+n <= len(r); j++ {
+		if r[j] == '\\' {
+			j++
+			continue
+		}
+		if runLen(r, j, c) < n || unicode.IsSpace(r[j-1]) {
+			continue
+		}
+		if c == '_' && j+n < len(r) && isWord(r[j+n]) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+// closeLink returns the offset after the ] that closes the [ at i, or -1
+func closeLink(r []rune, i int) int {
+	for j := i + 1; j < len(r); j++ {
+		switch r[j] {
+		case '\\':
+			j++
+		case '[':
+			return -1
+		case ']':
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// closeWiki returns the offset after a [[wiki link]] at i, or -1
+func closeWiki(r []rune, i int) int {
+	if !hasPrefix(r[i:], "[[") {
+		return -1
+	}
+	for j := i + 2; j+1 < len(r); j++ {
+		switch {
+		case r[j] == ']' && r[j+1] == ']' && j > i+2:
+			return j + 2
+		case r[j] == '[' || r[j] == ']':
+			return -1
+		}
+	}
+	return -1
+}
+
+// closeDest returns the offset after a (destination) or [reference] at i,
+// or -1
+func closeDest(r []rune, i int) int {
+	if i >= len(r) || r[i] != '(' && r[i] != '[' {
+		return -1
+	}
+	closer := ')'
+	if r[i] == '[' {
+		closer = ']'
+	}
+	for j := i + 1; j < len(r); j++ {
+		if r[j] == closer {
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// opensFence returns the fence a line opens a fenced code block with,
+// or ""
+func opensFence(line string) string {
+	t := strings.TrimLeft(line, " ")
+	if len(line)-len(t) > 3 || len(t) < 3 || t[0] != '`' && t[0] != '~' {
+		return ""
+	}
+	n := strings.IndexFunc(t, func(c rune) bool { return c != rune(t[0]) })
+	if n < 0 {
+		n = len(t)
+	}
+	if n < 3 || t[0] == '`' && strings.Contains(t[n:], "`") {
+		return ""
+	}
+	return t[:n]
+}
+
+// closesFence returns true if line closes a block opened with fence
+func closesFence(line, fence string) bool {
+	t := strings.TrimLeft(line, " ")
+	if len(line)-len(t) > 3 {
+		return false
+	}
+	t = strings.TrimRight(t, " \t")
+	return len(t) >= len(fence) && strings.Trim(t, fence[:1]) == ""
+}
+
+// isBreak returns true if line is a thematic break, like --- or * * *
+func isBreak(line string) bool {
+	t := strings.TrimSpace(line)
+	if t == "" || !strings.ContainsRune("-*_", rune(t[0])) {
+		return false
+	}
+	n := 0
+	for _, c := range t {
+		switch {
+		case c == rune(t[0]):
+			n++
+		case c != ' ' && c != '\t':
+			return false
+		}
+	}
+	return n >= 3
+}
+
+// isHeading returns true if r starts an ATX heading
+func isHeading(r []rune) bool {
+	n := runLen(r, 0, '#')
+	return n >= 1 && n <= 6 && (n == len(r) || r[n] == ' ' || r[n] == '\t')
+}
+
+// listMarker returns the length of the list marker r starts with,
+// including the space after it, or 0
+func listMarker(r []rune) int {
+	n := 0
+	switch {
+	case len(r) > 0 && strings.ContainsRune("-*+", r[0]):
+		n = 1
+	default:
+		for n < len(r) && n < 9 && unicode.IsDigit(r[n]) {
+			n++
+		}
+		if n == 0 || n >= len(r) || r[n] != '.' && r[n] != ')' {
+			return 0
+		}
+		n++
+	}
+	switch {
+	case n == len(r):
+		return n
+	case r[n] == ' ' || r[n] == '\t':
+		return n + 1
+	}
+	return 0
+}
+
+func skipSpaces(r []rune, i int) int {
+	for i < len(r) && (r[i] == ' ' || r[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// runLen returns how many c's follow one another from i
+func runLen(r []rune, i int, c rune) int {
+	n := 0
+	for i+n < len(r) && r[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// findRun returns where a run of exactly n c's starts at or after i, or -1
+func findRun(r []rune, i int, c rune, n int) int {
+	for i < len(r) {
+		if k := runLen(r, i, c); k > 0 {
+			if k == n {
+				return i
+			}
+			i += k
+			continue
+		}
+		i++
+	}
+	return -1
+}
+
+func hasPrefix(r []rune, prefix string) bool {
+	return len(r) >= len(prefix) && string(r[:len(prefix)]) == prefix
+}
+
+func isWord(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,281 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gioui.org/layout"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/markdown"
+	"gioui.org/x/richtext"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff"
+
+	"giopad/app"
+	"giopad/fs"
+	"giopad/links"
+)
+
+// attachmentsDir is the folder at the vault root where images not found
+// beside their note are looked for
+const attachmentsDir = "attachments"
+
+// maxCachedImages is how many decoded images are kept across notes
+const maxCachedImages = 32
+
+// errRemoteImage is the reason images on the web are not shown
+var errRemoteImage = errors.New("not a local file")
+
+// picture is an image of the preview, loaded in the background the first
+// time it is shown
+type picture struct {
+	dest   string // as written in the note
+	alt    string
+	width  int              // the width in pixels it was loaded for
+	result chan loadedImage // set while a load is underway
+	loaded bool
+	src    paint.ImageOp
+	err    error
+}
+
+// loadedImage is what a load of a picture came back with
+type loadedImage struct {
+	src paint.ImageOp
+	err error
+}
+
+// imageLoad reads and decodes an image, scaled down to width pixels if it
+// is wider. It may run on any goroutine.
+type imageLoad func(width int) (paint.ImageOp, error)
+
+// imageCache holds images as shown, by path and modification time, most
+// recently used last. Loads run on their own goroutines, so it is locked.
+var imageCache struct {
+	sync.Mutex
+	keys   []imageKey
+	images map[imageKey]cachedImage
+}
+
+// imageKey is an image file as it was when read
+type imageKey struct {
+	path    string
+	modTime int64
+}
+
+// cachedImage is a decoded image, scaled down to width pixels if scaled
+type cachedImage struct {
+	src    paint.ImageOp
+	width  int
+	scaled bool
+}
+
+// splitImages splits rendered spans where the renderer left images, so
+// each image can be laid out between the text around it
+func splitImages(spans []richtext.SpanStyle) (texts [][]richtext.SpanStyle, pics []*picture) {
+	start := 0
+	for i, s := range spans {
+		dest, ok := s.Get(markdown.MetadataImage).(string)
+		if !ok {
+			continue
+		}
+		alt, _ := s.Get(markdown.MetadataAlt).(string)
+		texts = append(texts, spans[start:i])
+		pics = append(pics, &picture{dest: dest, alt: alt})
+		start = i + 1
+	}
+	return append(texts, spans[start:]), pics
+}
+
+// loadImage returns the load of the image at dest, a destination in the
+// open note. What it needs of the editor is taken now, so the load can run
+// off the UI goroutine.
+func (e *Editor) loadImage(dest string) imageLoad {
+	l := imageLookup{fsys: e.fsys, root: e.root, from: e.currentPath, notes: e.links}
+	return func(width int) (paint.ImageOp, error) {
+		p, vfs, err := l.path(dest)
+		if err != nil {
+			return paint.ImageOp{}, err
+		}
+		entry, err := vfs.Stat(p)
+		if err != nil {
+			return paint.ImageOp{}, err
+		}
+		key := imageKey{p, entry.ModTime.UnixNano()}
+		if c, ok := cachedImageAt(key, width); ok {
+			return c, nil
+		}
+		data, err := vfs.ReadFile(p)
+		if err != nil {
+			return paint.ImageOp{}, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return paint.ImageOp{}, err
+		}
+		c := cachedImage{width: width}
+		if b := img.Bounds(); width > 0 && b.Dx() > width {
+			scaled := image.NewRGBA(image.Rect(0, 0, width, max(b.Dy()*width/b.Dx(), 1)))
+			draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, b, draw.Src, nil)
+			img, c.scaled = scaled, true
+		}
+		c.src = paint.NewImageOp(img)
+		cacheImage(key, c)
+		return c.src, nil
+	}
+}
+
+// cachedImageAt returns the cached image for key if it is at least width
+// pixels wide or was never scaled down, marking it recently used
+func cachedImageAt(key imageKey, width int) (paint.ImageOp, bool) {
+	imageCache.Lock()
+	defer imageCache.Unlock()
+	c, ok := imageCache.images[key]
+	if !ok || (c.scaled && c.width < width) {
+		return paint.ImageOp{}, false
+	}
+	for i, cached := range imageCache.keys {
+		if cached == key {
+			imageCache.keys = append(append(imageCache.keys[:i:i], imageCache.keys[i+1:]...), key)
+			break
+		}
+	}
+	return c.src, true
+}
+
+// cacheImage keeps c under key, unless a copy at least as wide is kept
+// already, dropping the least recently used image if the cache is full
+func cacheImage(key imageKey, c cachedImage) {
+	imageCache.Lock()
+	defer imageCache.Unlock()
+	if imageCache.images == nil {
+		imageCache.images = make(map[imageKey]cachedImage)
+	}
+	if old, ok := imageCache.images[key]; ok {
+		if !old.scaled || old.width >= c.width {
+			return
+		}
+	} else {
+		if len(imageCache.keys) >= maxCachedImages {
+			delete(imageCache.images, imageCache.keys[0])
+			imageCache.keys = imageCache.keys[1:]
+		}
+		imageCache.keys = append(imageCache.keys, key)
+	}
+	imageCache.images[key] = c
+}
+
+// imageLookup is what finding an image needs of the editor
+type imageLookup struct {
+	fsys  fs.VaultFS
+	root  string // vault root, or "" if none
+	from  string // the open note
+	notes *links.Notes
+}
+
+// path finds the file an image in the open note points at, and the
+// filesystem to read it from: beside the note, or failing that in the
+// vault's attachments folder. content:// and file: URLs are taken as
+// they are.
+func (l imageLookup) path(dest string) (string, fs.VaultFS, error) {
+	t, _ := l.notes.Resolve(l.from, dest)
+	switch {
+	case fs.IsSAFURI(t.URL):
+		return t.URL, l.fsys, nil
+	case strings.HasPrefix(t.URL, "file://"):
+		return filepath.FromSlash(strings.TrimPrefix(t.URL, "file://")), fs.OSFS{}, nil
+	case t.External():
+		return "", nil, errRemoteImage
+	}
+	if filepath.IsAbs(dest) && !fs.IsSAFURI(l.from) {
+		if _, err := os.Stat(dest); err == nil {
+			return dest, fs.OSFS{}, nil
+		}
+	}
+	if t.Path != "" {
+		return t.Path, l.fsys, nil
+	}
+	if l.root == "" || t.Rel == "" {
+		return "", nil, os.ErrNotExist
+	}
+	for _, rel := range []string{t.Rel, path.Join(attachmentsDir, path.Base(t.Rel))} {
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		entry, ok, err := fs.FindRel(l.fsys, l.root, rel)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok && !entry.IsDir {
+			return entry.Path, l.fsys, nil
+		}
+	}
+	return "", nil, os.ErrNotExist
+}
+
+// layoutImage draws an image as wide as it is in pixels, as dp, or as the
+// preview if that is narrower. Until it has loaded, or if it cannot be
+// shown, its alt text stands in with why.
+func (p *preview) layoutImage(gtx C, th *material.Theme, pic *picture) D {
+	if pic.result != nil {
+		select {
+		case r := <-pic.result:
+			pic.result, pic.loaded = nil, true
+			pic.src, pic.err = r.src, r.err
+		default:
+		}
+	}
+	width := gtx.Constraints.Max.X
+	wider := pic.loaded && pic.err == nil && width > pic.width && pic.src.Size().X >= pic.width
+	if pic.result == nil && (!pic.loaded || wider) {
+		// Load again when the preview grows past an image scaled down
+		load, result := p.loadImage(pic.dest), make(chan loadedImage, 1)
+		pic.result, pic.width = result, width
+		go func() {
+			src, err := load(width)
+			result <- loadedImage{src, err}
+			p.invalidate()
+		}()
+	}
+	if !pic.loaded || pic.err != nil {
+		text := pic.dest + ": "
+		switch {
+		case !pic.loaded:
+			text += "loading"
+		case errors.Is(pic.err, os.ErrNotExist):
+			text += "not found"
+		default:
+			text += pic.err.Error()
+		}
+		if pic.alt != "" {
+			text = pic.alt + " (" + text + ")"
+		}
+		label := material.Body2(th, text)
+		label.Color = app.Comment()
+		return label.Layout(gtx)
+	}
+
+	size := pic.src.Size()
+	w := min(gtx.Dp(unit.Dp(size.X)), gtx.Constraints.Max.X)
+	h := size.Y * w / max(size.X, 1)
+	gtx.Constraints = layout.Exact(image.Pt(w, h))
+	return widget.Image{Src: pic.src, Fit: widget.Contain, Position: layout.NW}.Layout(gtx)
+}
+
+// invalidate redraws the window after an image loaded in the background
+func (e *Editor) invalidate() {
+	if e.Invalidate != nil {
+		e.Invalidate()
+	}
+}
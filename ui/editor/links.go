@@ -0,0 +1,122 @@
+package editor
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+
+	"giopad/app"
+	"giopad/links"
+)
+
+// resolve returns where dest, a link in the open note, points
+func (e *Editor) resolve(dest string) (links.Target, bool) {
+	return e.links.Resolve(e.currentPath, dest)
+}
+
+// linkColor colours links to missing notes apart from working ones
+func (e *Editor) linkColor(dest string) color.NRGBA {
+	if _, ok := e.resolve(dest); !ok {
+		return app.Red()
+	}
+	return app.Blue()
+}
+
+// followLink hands a clicked link to OnLink. Anchors into the open note
+// are followed right here.
+func (e *Editor) followLink(dest string) {
+	t, ok := e.resolve(dest)
+	if ok && !t.External() && t.Path == e.currentPath {
+		e.GoToAnchor(t.Anchor)
+		return
+	}
+	if e.OnLink != nil {
+		e.OnLink(t, ok)
+	}
+}
+
+// GoToAnchor scrolls to the heading anchor links to, keeping the mode.
+// An empty or unknown anchor leaves the view where it is.
+func (e *Editor) GoToAnchor(anchor string) {
+	if anchor == "" {
+		return
+	}
+	line := anchorLine(e.textEditor.Text(), anchor)
+	if line < 0 {
+		return
+	}
+	if e.mode != View {
+		e.GoToLine(line)
+		return
+	}
+	pos := e.lineOffset(line)
+	e.textEditor.SetCaret(pos, pos)
+	e.preview.list.Position = layout.Position{First: e.preview.find(line)}
+}
+
+// anchorLine returns the line of the heading anchor links to, or -1
+func anchorLine(src, anchor string) int {
+	fence := ""
+	for i, line := range strings.Split(src, "\n") {
+		switch {
+		case fence != "":
+			if closesFence(line, fence) {
+				fence = ""
+			}
+		case opensFence(line) != "":
+			fence = opensFence(line)
+		default:
+			t := strings.TrimLeft(line, " ")
+			if isHeading([]rune(t)) && links.Slug(strings.Trim(t, "# \t")) == anchor {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// layoutHover shows where the link under the pointer goes, in the bottom
+// left corner like a browser's status bar
+func (e *Editor) layoutHover(gtx C, th *material.Theme, dest string) {
+	text := dest
+	t, ok := e.resolve(dest)
+	switch {
+	case t.External() && !ok:
+		text = dest + " (not opened)"
+	case t.External():
+	case !ok:
+		text = t.Rel + " (missing)"
+	case t.Path == e.currentPath:
+		text = "#" + t.Anchor
+	case t.Rel != "":
+		text = t.Rel
+		if t.Anchor != "" {
+			text += "#" + t.Anchor
+		}
+	}
+
+	m := op.Record(gtx.Ops)
+	gtx.Constraints.Min = image.Point{}
+	dims := layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+		label := material.Caption(th, text)
+		label.MaxLines = 1
+		label.Color = app.Comment()
+		if !ok {
+			label.Color = app.Red()
+		}
+		return label.Layout(gtx)
+	})
+	call := m.Stop()
+
+	y := gtx.Constraints.Max.Y - dims.Size.Y
+	defer op.Offset(image.Pt(0, y)).Push(gtx.Ops).Pop()
+	paint.FillShape(gtx.Ops, app.Surface(), clip.Rect(image.Rectangle{Max: dims.Size}).Op())
+	call.Add(gtx.Ops)
+}
@@ -0,0 +1,453 @@
+package editor
+
+import (
+	"bytes"
+	"image"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gioui.org/gesture"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"gioui.org/x/markdown"
+	"gioui.org/x/richtext"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// previewDelay is how long typing has to pause before the split preview
+// is rendered again
+const previewDelay = 250 * time.Millisecond
+
+// blockParser finds the top-level blocks of a note. The renderer has no
+// tables, so they are parsed here and laid out by the preview itself.
+// Task lists are parsed to find the checkboxes clicked in the preview.
+var blockParser parser.Parser = goldmark.New(goldmark.WithExtensions(extension.Table, extension.TaskList)).Parser()
+
+// codePartLines is how many lines of a long code block go in one block of
+// the preview
+const codePartLines = 40
+
+// kind is what sort of markdown a block of the preview shows
+type kind uint8
+
+const (
+	kindOther kind = iota // HTML, front matter and the like
+	kindParagraph
+	kindHeading
+	kindList // one item of a top-level list
+	kindQuote
+	kindCode // a code block, or some lines of a long one
+	kindTable
+	kindImage
+)
+
+// block is one item of the preview's list: a top-level markdown block or
+// an item of a top-level list, or a part of either. Long code is split
+// every few lines, and text around the images in it. Only the blocks on
+// screen are laid out, so no one block should be long.
+type block struct {
+	kind     kind
+	source   string // markdown the block was rendered from
+	part     int    // index among the parts of its source; 0 for the first
+	joined   bool   // continues the block above, with no gap between them
+	line     int    // source line it starts on, 0-based
+	offset   int    // rune offset of that line in the source
+	partLine int    // lines of the source before this part
+	partRune int    // runes of the source before this part
+	spans    []richtext.SpanStyle
+	table    *table     // set instead of spans for a table
+	image    *picture   // set instead of spans for an image
+	code     *codeBlock // set, along with spans, for code
+	state    richtext.InteractiveText
+	click    gesture.Click
+	height   int // at the last layout; 0 until laid out
+}
+
+// preview renders a note one top-level block at a time, so each rendered
+// block can be matched to the source lines it came from
+type preview struct {
+	blocks    []*block
+	lines     int    // lines in the source
+	refs      string // the link reference definitions last rendered with
+	list      layout.List
+	due       time.Time // when to render again after an edit; zero if not pending
+	loadImage func(dest string) imageLoad
+	// invalidate redraws the window once an image has loaded
+	invalidate func()
+
+	// clicked is the source line last clicked, or -1
+	clicked int
+	// followed is the destination of the link last clicked, or ""
+	followed string
+	// hover is the destination of the link under the pointer, or ""
+	hover string
+	// toggled is the rune offset in the source of the mark of the task
+	// checkbox last clicked, or -1
+	toggled int
+}
+
+func newPreview(loadImage func(dest string) imageLoad, invalidate func()) *preview {
+	return &preview{list: layout.List{Axis: layout.Vertical}, loadImage: loadImage, invalidate: invalidate, clicked: -1, toggled: -1}
+}
+
+// render splits src into blocks and renders each one. Blocks whose source
+// did not change keep their spans and state.
+func (p *preview) render(r *markdown.Renderer, src []byte) {
+	old := make(map[string][]*block, len(p.blocks))
+	for i, b := range p.blocks {
+		if b.part == 0 {
+			j := i + 1
+			for j < len(p.blocks) && p.blocks[j].part > 0 {
+				j++
+			}
+			old[b.source] = p.blocks[i:j:j]
+		}
+	}
+	p.blocks = make([]*block, 0, len(p.blocks))
+	p.lines = bytes.Count(src, []byte("\n")) + 1
+	p.due = time.Time{}
+
+	chunks, refs := splitBlocks(src)
+	if key := refs.String(); key != p.refs {
+		// Links anywhere may point at a definition that changed
+		p.refs = key
+		clear(old)
+	}
+	line, offset, prev := 0, 0, 0
+	for i, c := range chunks {
+		start, end := c.start, len(src)
+		if i+1 < len(chunks) {
+			end = chunks[i+1].start
+		}
+		line += bytes.Count(src[prev:start], []byte("\n"))
+		offset += utf8.RuneCount(src[prev:start])
+		prev = start
+
+		parts, ok := old[string(src[start:end])]
+		if ok {
+			delete(old, string(src[start:end])) // The same text twice needs two blocks
+		} else {
+			parts = renderBlock(r, src[start:end], refs)
+		}
+		for _, b := range parts {
+			b.line, b.offset = line+b.partLine, offset+b.partRune
+		}
+		parts[0].joined = c.joined
+		if c.number > 0 && parts[0].kind == kindList {
+			numberItem(parts[0].spans, c.number)
+		}
+		p.blocks = append(p.blocks, parts...)
+	}
+}
+
+// renderBlock renders the markdown of one block of the source: a table,
+// a code block, or text with any images in it as parts of their own. It
+// returns at least one block.
+func renderBlock(r *markdown.Renderer, src []byte, refs references) []*block {
+	k := kindOther
+	doc := blockParser.Parse(text.NewReader(src))
+	if n := doc.FirstChild(); n != nil {
+		switch n := n.(type) {
+		case *extast.Table:
+			if alone(n) {
+				return []*block{{kind: kindTable, source: string(src), table: newTable(r, src, n, refs)}}
+			}
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			if alone(n) {
+				return renderCode(r, src, n)
+			}
+			k = kindCode
+		case *ast.Paragraph:
+			k = kindParagraph
+		case *ast.Heading:
+			k = kindHeading
+		case *ast.List:
+			k = kindList
+		case *ast.Blockquote:
+			k = kindQuote
+		}
+	}
+
+	spans, err := r.Render(src, refs.option())
+	if err != nil {
+		spans = []richtext.SpanStyle{{Content: string(src), Color: r.Config.DefaultColor, Size: r.Config.DefaultSize}}
+	}
+	texts, pics := splitImages(spans)
+	var parts []*block
+	for i, text := range texts {
+		if i > 0 {
+			parts = append(parts, &block{kind: kindImage, source: string(src), part: len(parts), image: pics[i-1]})
+			text = trimLeadingSpans(text)
+		}
+		if text = trimSpans(text); len(text) > 0 || len(texts) == 1 {
+			parts = append(parts, &block{kind: k, source: string(src), part: len(parts), spans: text})
+		}
+	}
+	return parts
+}
+
+// forget drops the rendered blocks, so the next render starts afresh
+func (p *preview) forget() {
+	p.blocks = nil
+	p.hover = ""
+}
+
+// empty returns true if there is nothing to show
+func (p *preview) empty() bool {
+	for _, b := range p.blocks {
+		if len(b.spans) > 0 || b.kind == kindTable || b.kind == kindImage || b.kind == kindCode {
+			return false
+		}
+	}
+	return true
+}
+
+// chunk is where a block of the source begins
+type chunk struct {
+	start  int  // byte offset
+	joined bool // an item of a list after its first
+	number int  // the number of an ordered list item, or 0
+}
+
+// alone reports whether n is the only block of its document. Link
+// reference definitions after it leave an empty paragraph, which is not
+// counted.
+func alone(n ast.Node) bool {
+	for next := n.NextSibling(); next != nil; next = next.NextSibling() {
+		empty := next.Kind() == ast.KindParagraph || next.Kind() == ast.KindTextBlock
+		if !empty || next.Lines().Len() > 0 || next.HasChildren() {
+			return false
+		}
+	}
+	return true
+}
+
+// references are the link reference definitions of a note. Blocks are
+// rendered one at a time, so each is given all of them to resolve the
+// [text][label] links in it.
+type references []parser.Reference
+
+// option returns a parse option giving the definitions to a parse
+func (refs references) option() parser.ParseOption {
+	pc := parser.NewContext()
+	for _, ref := range refs {
+		pc.AddReference(ref)
+	}
+	return parser.WithContext(pc)
+}
+
+// String returns the definitions as text, the same whatever their order
+func (refs references) String() string {
+	lines := make([]string, len(refs))
+	for i, ref := range refs {
+		lines[i] = ref.String()
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// splitBlocks returns where the top-level blocks of src begin, and the
+// items of top-level lists, so a long list is many short blocks. The first
+// always starts at 0 so nothing before it is lost, and blocks without
+// source lines, like thematic breaks, stay with the block before them.
+// It also returns the link reference definitions of src.
+func splitBlocks(src []byte) ([]chunk, references) {
+	chunks := []chunk{{}}
+	add := func(n ast.Node, joined bool, number int) {
+		start, ok := blockStart(n, src)
+		if ok && start > chunks[len(chunks)-1].start {
+			chunks = append(chunks, chunk{start, joined, number})
+		}
+	}
+	pc := parser.NewContext()
+	doc := blockParser.Parse(text.NewReader(src), parser.WithContext(pc))
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		list, ok := n.(*ast.List)
+		if !ok {
+			add(n, false, 0)
+			continue
+		}
+		number := 0
+		if list.IsOrdered() {
+			number = list.Start
+		}
+		for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+			add(item, item != n.FirstChild(), number)
+			if number > 0 {
+				number++
+			}
+		}
+	}
+	return chunks, pc.References()
+}
+
+// blockStart returns the offset of the start of the first source line of
+// n or its first descendant that has lines
+func blockStart(n ast.Node, src []byte) (int, bool) {
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		start := lineStart(src, n.Lines().At(0).Start)
+		if n.Kind() == ast.KindFencedCodeBlock && start > 0 {
+			// The lines are the code; the opening fence is the line above
+			start = lineStart(src, start-1)
+		}
+		return start, true
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if start, ok := blockStart(c, src); ok {
+			return start, true
+		}
+	}
+	return 0, false
+}
+
+// lineStart returns the offset of the start of the line holding offset i
+func lineStart(src []byte, i int) int {
+	return bytes.LastIndexByte(src[:i], '\n') + 1
+}
+
+// trimSpans drops the blank lines the renderer leaves at the end of a
+// block; the preview spaces blocks itself
+func trimSpans(spans []richtext.SpanStyle) []richtext.SpanStyle {
+	for len(spans) > 0 {
+		last := &spans[len(spans)-1]
+		last.Content = strings.TrimRight(last.Content, "\n")
+		if last.Content != "" {
+			break
+		}
+		spans = spans[:len(spans)-1]
+	}
+	return spans
+}
+
+// numberItem renumbers the marker of an ordered list item rendered on its
+// own, which the renderer would number 1
+func numberItem(spans []richtext.SpanStyle, number int) {
+	if len(spans) > 0 && strings.HasPrefix(spans[0].Content, " ") && strings.HasSuffix(spans[0].Content, ". ") {
+		spans[0].Content = " " + strconv.Itoa(number) + ". "
+	}
+}
+
+// trimLeadingSpans drops the line breaks left at the start of the text
+// after an image
+func trimLeadingSpans(spans []richtext.SpanStyle) []richtext.SpanStyle {
+	for len(spans) > 0 {
+		spans[0].Content = strings.TrimLeft(spans[0].Content, "\n")
+		if spans[0].Content != "" {
+			break
+		}
+		spans = spans[1:]
+	}
+	return spans
+}
+
+// nextLine returns the source line after block i
+func (p *preview) nextLine(i int) int {
+	if i+1 < len(p.blocks) {
+		return p.blocks[i+1].line
+	}
+	return p.lines
+}
+
+// find returns the last block that starts at or before line, or the
+// first of its parts that starts on the same line
+func (p *preview) find(line int) int {
+	i := sort.Search(len(p.blocks)-1, func(i int) bool {
+		return p.blocks[i+1].line > line
+	})
+	for i > 0 && p.blocks[i].part > 0 && p.blocks[i-1].line == p.blocks[i].line {
+		i--
+	}
+	return i
+}
+
+// layout renders the blocks in a scrolling list. Clicking a block sets
+// clicked to the source line under the pointer.
+func (p *preview) layout(gtx C, th *material.Theme) D {
+	return p.list.Layout(gtx, len(p.blocks), func(gtx C, i int) D {
+		b := p.blocks[i]
+		link := false
+		handle := func(span *richtext.InteractiveSpan, ev richtext.Event) {
+			if task, ok := span.Get(markdown.MetadataTask).(int); ok {
+				// While a render is due the blocks may trail the source
+				if ev.Type == richtext.Click && p.due.IsZero() {
+					if mark := taskMark([]byte(b.source), task); mark >= 0 {
+						p.toggled, link = b.offset-b.partRune+utf8.RuneCountInString(b.source[:mark]), true
+					}
+				}
+				return
+			}
+			dest, _ := span.Get(markdown.MetadataURL).(string)
+			switch ev.Type {
+			case richtext.Click:
+				p.followed, link = dest, true
+			case richtext.Hover:
+				p.hover = dest
+			case richtext.Unhover:
+				if p.hover == dest {
+					p.hover = ""
+				}
+			}
+		}
+		for {
+			span, ev, ok := b.state.Update(gtx)
+			if !ok {
+				break
+			}
+			handle(span, ev)
+		}
+		if b.kind == kindTable {
+			b.table.update(gtx, handle)
+		}
+		for {
+			ev, ok := b.click.Update(gtx.Source)
+			if !ok {
+				break
+			}
+			if ev.Kind == gesture.KindClick && b.height > 0 && !link {
+				// Spread the block's source lines over its height
+				lines := p.nextLine(i) - b.line
+				p.clicked = b.line + min(int(ev.Position.Y)*lines/b.height, max(lines-1, 0))
+			}
+		}
+
+		// Record the block first so the click area can sit beneath its
+		// links, not on top of them
+		gap := unit.Dp(12)
+		if i+1 < len(p.blocks) && p.blocks[i+1].joined {
+			gap = 0
+		}
+		m := op.Record(gtx.Ops)
+		dims := layout.Inset{Bottom: gap}.Layout(gtx, func(gtx C) D {
+			switch b.kind {
+			case kindTable:
+				return b.table.layout(gtx, th)
+			case kindImage:
+				return p.layoutImage(gtx, th, b.image)
+			case kindCode:
+				if b.code != nil {
+					return p.layoutCode(gtx, th, b)
+				}
+			}
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			return richtext.Text(&b.state, th.Shaper, b.spans...).Layout(gtx)
+		})
+		call := m.Stop()
+		defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
+		b.click.Add(gtx.Ops)
+		call.Add(gtx.Ops)
+		b.height = dims.Size.Y
+		return dims
+	})
+}
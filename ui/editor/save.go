@@ -0,0 +1,152 @@
+package editor
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"giopad/fs"
+)
+
+// ErrDiskChanged is returned by Save when the file on disk changed since it
+// was loaded or last saved. Settle it with ForceSave, ReloadFromDisk or
+// SaveCopy.
+var ErrDiskChanged = errors.New("file changed on disk")
+
+// diskState is what the file looked like when we last read or wrote it
+type diskState struct {
+	modTime time.Time
+	hash    [sha256.Size]byte
+}
+
+// recordDisk remembers the on-disk version of the current file
+func (e *Editor) recordDisk(content []byte) {
+	e.disk.hash = sha256.Sum256(content)
+	e.disk.modTime = time.Time{}
+	if info, err := e.fsys.Stat(e.currentPath); err == nil {
+		e.disk.modTime = info.ModTime
+	}
+}
+
+// checkDiskUnchanged returns ErrDiskChanged if someone else wrote the file.
+// A newer mtime with identical content (a touch, a sync no-op) is fine.
+func (e *Editor) checkDiskUnchanged() error {
+	info, err := e.fsys.Stat(e.currentPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // Deleted under us; saving recreates it
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime.Equal(e.disk.modTime) {
+		return nil
+	}
+	content, err := e.fsys.ReadFile(e.currentPath)
+	if err != nil {
+		return err
+	}
+	if sha256.Sum256(content) != e.disk.hash {
+		return ErrDiskChanged
+	}
+	e.disk.modTime = info.ModTime
+	return nil
+}
+
+// Save writes content to disk, refusing to clobber a newer version
+func (e *Editor) Save() error {
+	if e.currentPath == "" || !e.IsDirty() {
+		return nil
+	}
+	if err := e.checkDiskUnchanged(); err != nil {
+		return err
+	}
+	return e.write()
+}
+
+// ForceSave writes content to disk even if the file changed there
+func (e *Editor) ForceSave() error {
+	if e.currentPath == "" {
+		return nil
+	}
+	return e.write()
+}
+
+func (e *Editor) write() error {
+	content := []byte(e.textEditor.Text())
+	if err := e.fsys.WriteFile(e.currentPath, content); err != nil {
+		return err
+	}
+	e.wrote(content)
+	return nil
+}
+
+// wrote records content as what the open file now holds on disk
+func (e *Editor) wrote(content []byte) {
+	e.savedContent = content
+	e.updateDirty()
+	e.recordDisk(content)
+	e.conflict = false
+	e.diskContent = nil
+}
+
+// SaveRecovery writes the buffer to path, outside the vault, leaving the
+// note on disk and the open file as they are
+func (e *Editor) SaveRecovery(path string) error {
+	if e.currentPath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return fs.WriteFileAtomic(path, []byte(e.textEditor.Text()), 0o644)
+}
+
+// Recover puts content in the buffer as unsaved edits to the open file,
+// as kept by SaveRecovery
+func (e *Editor) Recover(content []byte) {
+	if e.currentPath == "" {
+		return
+	}
+	saved := e.savedContent
+	e.reload(content)
+	e.savedContent = saved
+	e.updateDirty()
+}
+
+// ReloadFromDisk replaces the buffer with the file's current contents,
+// dropping unsaved edits
+func (e *Editor) ReloadFromDisk() error {
+	if e.currentPath == "" {
+		return nil
+	}
+	content, err := e.fsys.ReadFile(e.currentPath)
+	if err != nil {
+		return err
+	}
+	e.reload(content)
+	e.recordDisk(content)
+	return nil
+}
+
+// SaveCopy writes the buffer to a new "name (copy).md" file in dir and
+// makes that the open file. dir must be the current file's directory as
+// the vault filesystem knows it. Returns the new path.
+func (e *Editor) SaveCopy(dir string) (string, error) {
+	if e.currentPath == "" {
+		return "", nil
+	}
+	name := filepath.Base(e.currentPath)
+	if info, err := e.fsys.Stat(e.currentPath); err == nil && info.Name != "" {
+		name = info.Name
+	}
+	content := []byte(e.textEditor.Text())
+	path, err := fs.WriteCopy(e.fsys, dir, name, content)
+	if err != nil {
+		return "", err
+	}
+	e.currentPath = path
+	e.wrote(content)
+	return path, nil
+}
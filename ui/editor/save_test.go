@@ -0,0 +1,45 @@
+package editor
+
+import (
+	"testing"
+
+	"giopad/fs"
+)
+
+func TestDirty(t *testing.T) {
+	m := fs.NewMemFS()
+	if err := m.MkdirAll("/vault"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteFile("/vault/a.md", []byte("- [ ] one")); err != nil {
+		t.Fatal(err)
+	}
+	e := New()
+	e.SetFS(m, "/vault")
+	if err := e.LoadFile("/vault/a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if e.IsDirty() {
+		t.Error("dirty right after loading")
+	}
+
+	e.toggleTask(3)
+	if !e.IsDirty() {
+		t.Error("not dirty after toggling a task")
+	}
+	if err := e.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if e.IsDirty() {
+		t.Error("dirty after saving")
+	}
+
+	e.Recover([]byte("- [ ] one, edited"))
+	if !e.IsDirty() {
+		t.Error("not dirty after recovering edits")
+	}
+	e.Revert()
+	if e.IsDirty() {
+		t.Error("dirty after reverting")
+	}
+}
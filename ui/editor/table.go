@@ -0,0 +1,248 @@
+package editor
+
+import (
+	"bytes"
+	"image"
+
+	"gioui.org/font"
+	"gioui.org/gesture"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"gioui.org/x/markdown"
+	"gioui.org/x/richtext"
+
+	"giopad/app"
+
+	extast "github.com/yuin/goldmark/extension/ast"
+)
+
+// maxCellWidth is how wide a column grows before its cells wrap
+const maxCellWidth = unit.Dp(320)
+
+// table is a GFM pipe table of the preview, laid out as a grid
+type table struct {
+	align  []extast.Alignment
+	rows   [][]*cell // the header row first
+	scroll gesture.Scroll
+	offset int // horizontal scroll, in pixels
+}
+
+// cell is one rendered table cell
+type cell struct {
+	spans []richtext.SpanStyle
+	state richtext.InteractiveText
+}
+
+// newTable renders the table n of src, resolving links with refs
+func newTable(r *markdown.Renderer, src []byte, n *extast.Table, refs references) *table {
+	t := &table{align: n.Alignments}
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		_, header := row.(*extast.TableHeader)
+		var cells []*cell
+		for c := row.FirstChild(); c != nil; c = c.NextSibling() {
+			var content []byte
+			for i := 0; i < c.Lines().Len(); i++ {
+				seg := c.Lines().At(i)
+				content = append(content, seg.Value(src)...)
+			}
+			// An escaped pipe is part of the cell, even in a code span
+			content = bytes.ReplaceAll(content, []byte(`\|`), []byte("|"))
+			content = inlineOnly(content)
+			spans, err := r.Render(content, refs.option())
+			if err != nil {
+				spans = []richtext.SpanStyle{{Content: string(content), Color: r.Config.DefaultColor, Size: r.Config.DefaultSize}}
+			}
+			spans = trimSpans(spans)
+			if header {
+				for i := range spans {
+					spans[i].Font.Weight = font.Bold
+				}
+			}
+			cells = append(cells, &cell{spans: spans})
+		}
+		t.rows = append(t.rows, cells)
+	}
+	return t
+}
+
+// inlineOnly escapes whatever at the start of a cell's content would
+// begin a block, so that "1. first", "- item" or "# tag" stay text in the
+// table as GFM has them, rather than a list or heading
+func inlineOnly(content []byte) []byte {
+	s := bytes.TrimLeft(content, " \t")
+	at := -1
+	switch {
+	case len(s) == 0:
+	case s[0] == '#' || s[0] == '>':
+		at = 0
+	case bytes.HasPrefix(s, []byte("```")) || bytes.HasPrefix(s, []byte("~~~")):
+		at = 0
+	case s[0] == '-' || s[0] == '+' || s[0] == '*':
+		if len(s) == 1 || s[1] == ' ' || s[1] == '\t' || thematicBreak(s) {
+			at = 0
+		}
+	case s[0] == '_':
+		if thematicBreak(s) {
+			at = 0
+		}
+	case s[0] == '[':
+		// A link reference definition, [label]: url
+		if end := bytes.IndexByte(s, ']'); end > 0 && bytes.HasPrefix(s[end+1:], []byte(":")) {
+			at = 0
+		}
+	case s[0] >= '0' && s[0] <= '9':
+		n := len(s) - len(bytes.TrimLeft(s, "0123456789"))
+		if n <= 9 && n < len(s) && (s[n] == '.' || s[n] == ')') && (n+1 == len(s) || s[n+1] == ' ' || s[n+1] == '\t') {
+			at = n
+		}
+	}
+	if at < 0 {
+		return s
+	}
+	escaped := make([]byte, 0, len(s)+1)
+	escaped = append(escaped, s[:at]...)
+	escaped = append(escaped, '\\')
+	return append(escaped, s[at:]...)
+}
+
+// thematicBreak returns true if line is a ---, *** or ___ rule: three or
+// more of the same mark and nothing else but blanks
+func thematicBreak(line []byte) bool {
+	marks := 0
+	for _, c := range line {
+		switch c {
+		case line[0]:
+			marks++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return marks >= 3
+}
+
+// update passes the link events of every cell to handle
+func (t *table) update(gtx C, handle func(*richtext.InteractiveSpan, richtext.Event)) {
+	for _, row := range t.rows {
+		for _, c := range row {
+			for {
+				span, ev, ok := c.state.Update(gtx)
+				if !ok {
+					break
+				}
+				handle(span, ev)
+			}
+		}
+	}
+}
+
+// layout draws the table with its header row shaded and a border round
+// every cell. A table wider than the space it has scrolls sideways.
+func (t *table) layout(gtx C, th *material.Theme) D {
+	pad := image.Pt(gtx.Dp(unit.Dp(8)), gtx.Dp(unit.Dp(4)))
+	border := max(gtx.Dp(unit.Dp(1)), 1)
+
+	// Lay every cell out first, to size the columns and rows to fit
+	cols := len(t.align)
+	for _, row := range t.rows {
+		cols = max(cols, len(row))
+	}
+	widths := make([]int, cols)
+	heights := make([]int, len(t.rows))
+	calls := make([][]op.CallOp, len(t.rows))
+	sizes := make([][]image.Point, len(t.rows))
+	for i, row := range t.rows {
+		for j, c := range row {
+			m := op.Record(gtx.Ops)
+			cgtx := gtx
+			cgtx.Constraints = layout.Constraints{Max: image.Pt(gtx.Dp(maxCellWidth), gtx.Constraints.Max.Y)}
+			dims := richtext.Text(&c.state, th.Shaper, c.spans...).Layout(cgtx)
+			calls[i] = append(calls[i], m.Stop())
+			sizes[i] = append(sizes[i], dims.Size)
+			widths[j] = max(widths[j], dims.Size.X+2*pad.X)
+			heights[i] = max(heights[i], dims.Size.Y+2*pad.Y)
+		}
+	}
+	for j := range widths {
+		widths[j] = max(widths[j], 2*pad.X)
+	}
+	for i := range heights {
+		heights[i] = max(heights[i], 2*pad.Y)
+	}
+	size := image.Pt(border, border)
+	for _, w := range widths {
+		size.X += w + border
+	}
+	for _, h := range heights {
+		size.Y += h + border
+	}
+
+	// Scroll what does not fit
+	view := min(size.X, gtx.Constraints.Max.X)
+	if size.X > view {
+		d := t.scroll.Update(gtx.Metric, gtx.Source, gtx.Now, gesture.Horizontal,
+			pointer.ScrollRange{Min: -t.offset, Max: size.X - view - t.offset}, pointer.ScrollRange{})
+		t.offset += d
+	}
+	t.offset = max(min(t.offset, size.X-view), 0)
+
+	height := size.Y
+	if size.X > view {
+		height += gtx.Dp(unit.Dp(6)) // Room for the scroll bar
+	}
+	defer clip.Rect(image.Rectangle{Max: image.Pt(view, height)}).Push(gtx.Ops).Pop()
+	if size.X > view {
+		t.scroll.Add(gtx.Ops)
+		thumb := view * view / size.X
+		x := (view - thumb) * t.offset / (size.X - view)
+		bar := image.Rect(x, size.Y+gtx.Dp(unit.Dp(2)), x+thumb, height)
+		paint.FillShape(gtx.Ops, app.Comment(), clip.UniformRRect(bar, bar.Dy()/2).Op(gtx.Ops))
+	}
+
+	trans := op.Offset(image.Pt(-t.offset, 0)).Push(gtx.Ops)
+	defer trans.Pop()
+	if len(t.rows) > 0 {
+		header := image.Rect(0, 0, size.X, heights[0]+2*border)
+		paint.FillShape(gtx.Ops, app.Surface(), clip.Rect(header).Op())
+	}
+	y := 0
+	for i := range t.rows {
+		paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(image.Rect(0, y, size.X, y+border)).Op())
+		y += border + heights[i]
+	}
+	paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(image.Rect(0, y, size.X, y+border)).Op())
+	x := 0
+	for _, w := range widths {
+		paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(image.Rect(x, 0, x+border, size.Y)).Op())
+		x += border + w
+	}
+	paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(image.Rect(x, 0, x+border, size.Y)).Op())
+
+	y = border
+	for i := range t.rows {
+		x := border
+		for j, call := range calls[i] {
+			free := widths[j] - 2*pad.X - sizes[i][j].X
+			shift := 0
+			if j < len(t.align) {
+				switch t.align[j] {
+				case extast.AlignCenter:
+					shift = free / 2
+				case extast.AlignRight:
+					shift = free
+				}
+			}
+			off := op.Offset(image.Pt(x+pad.X+shift, y+pad.Y)).Push(gtx.Ops)
+			call.Add(gtx.Ops)
+			off.Pop()
+			x += widths[j] + border
+		}
+		y += heights[i] + border
+	}
+	return D{Size: image.Pt(view, height)}
+}
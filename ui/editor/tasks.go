@@ -0,0 +1,65 @@
+package editor
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// taskMark returns the byte offset in src of the mark between the
+// brackets of its n-th task checkbox, counting from 0, or -1 if there is
+// no such checkbox. The renderer counts them in the same order.
+func taskMark(src []byte, n int) int {
+	mark := -1
+	doc := blockParser.Parse(text.NewReader(src))
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || node.Kind() != extast.KindTaskCheckBox {
+			return ast.WalkContinue, nil
+		}
+		if n > 0 {
+			n--
+			return ast.WalkContinue, nil
+		}
+		// The checkbox opens the first line of its item's text
+		if lines := node.Parent().Lines(); lines.Len() > 0 {
+			start := lines.At(0).Start
+			if i := bytes.IndexByte(src[start:], '['); i >= 0 {
+				mark = start + i + 1
+			}
+		}
+		return ast.WalkStop, nil
+	})
+	return mark
+}
+
+// toggleTask ticks or clears the task checkbox whose mark is at rune
+// offset at of the source. The change goes through the text editor, so it
+// makes the note dirty and can be undone. Nothing changes unless at is
+// still between the brackets of a checkbox.
+func (e *Editor) toggleTask(at int) {
+	before, rest := splitAtRune(e.textEditor.Text(), at)
+	r, size := utf8.DecodeRuneInString(rest)
+	if !strings.HasSuffix(before, "[") || !strings.HasPrefix(rest[size:], "]") {
+		return // The source moved under the click
+	}
+	var mark string
+	switch r {
+	case 'x', 'X':
+		mark = " "
+	case ' ', '\t':
+		mark = "x"
+	default:
+		return // The source moved under the click
+	}
+	start, end := e.textEditor.Selection()
+	e.textEditor.SetCaret(at, at+1)
+	e.textEditor.Insert(mark)
+	e.textEditor.SetCaret(start, end)
+	e.updateDirty()
+	e.restyle = true
+	e.preview.render(e.renderer, []byte(e.textEditor.Text()))
+}
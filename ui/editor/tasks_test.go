@@ -0,0 +1,62 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTaskMark(t *testing.T) {
+	src := "- [ ] one\n- [x] two\n\n```\n- [ ] code\n```\n\n1. [X] três\n   - [ ] nested\n"
+	tests := []struct {
+		n    int
+		want string // the line the mark is on
+	}{
+		{0, "- [ ] one"},
+		{1, "- [x] two"},
+		{2, "1. [X] três"},
+		{3, "   - [ ] nested"},
+		{4, ""},
+	}
+	for _, tt := range tests {
+		mark := taskMark([]byte(src), tt.n)
+		if tt.want == "" {
+			if mark >= 0 {
+				t.Errorf("taskMark(%d) = %d, want -1", tt.n, mark)
+			}
+			continue
+		}
+		if mark < 1 || src[mark-1] != '[' || src[mark+1] != ']' {
+			t.Errorf("taskMark(%d) = %d, not between brackets", tt.n, mark)
+			continue
+		}
+		start := strings.LastIndexByte(src[:mark], '\n') + 1
+		if line, _, _ := strings.Cut(src[start:], "\n"); line != tt.want {
+			t.Errorf("taskMark(%d) is on %q, want %q", tt.n, line, tt.want)
+		}
+	}
+}
+
+func TestToggleTask(t *testing.T) {
+	e := New()
+	e.textEditor.SetText("é text\n- [ ] one\n- [x] two")
+	mark := func(n int) int {
+		src := e.textEditor.Text()
+		return utf8.RuneCountInString(src[:taskMark([]byte(src), n)])
+	}
+
+	e.toggleTask(mark(0))
+	e.toggleTask(mark(1))
+	if got, want := e.textEditor.Text(), "é text\n- [x] one\n- [ ] two"; got != want {
+		t.Errorf("after toggling both: %q, want %q", got, want)
+	}
+
+	// An offset the text moved away from changes nothing
+	for _, at := range []int{0, 1, mark(0) - 1, mark(0) + 1, 100} {
+		before := e.textEditor.Text()
+		e.toggleTask(at)
+		if got := e.textEditor.Text(); got != before {
+			t.Errorf("toggleTask(%d) changed %q to %q", at, before, got)
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package merge
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"giopad/app"
+	"giopad/fs"
+	"giopad/internal/diff"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// Pick says which side of a changed hunk ends up in the merge
+type Pick int
+
+const (
+	PickOriginal Pick = iota
+	PickConflict
+	PickBoth
+)
+
+var pickLabels = [...]string{"Keep original", "Take conflict", "Both"}
+
+// contextLines is how many unchanged lines show around each hunk
+const contextLines = 3
+
+// ErrOriginalChanged is why a merge is not applied when the original was
+// written after the view read it
+var ErrOriginalChanged = errors.New("original changed on disk since the merge was opened")
+
+// View compares a Syncthing conflict copy with its original line by line
+// and lets the user pick a side for every changed hunk
+type View struct {
+	fsys         fs.VaultFS
+	trash        *fs.Trash // nil if the vault has none
+	originalPath string
+	conflictPath string
+	conflictRel  string // relative to the vault root, for the trash
+	originalName string
+	originalMod  time.Time         // of the original as Open read it
+	originalSum  [sha256.Size]byte // likewise
+	chunks       []diff.Chunk
+	picks        []Pick
+	pickClicks   [][len(pickLabels)]widget.Clickable
+	err          error
+	active       bool
+
+	applyClick  widget.Clickable
+	deleteClick widget.Clickable
+	closeClick  widget.Clickable
+	list        widget.List
+
+	onClose func(originalPath string, resolved bool)
+}
+
+// New creates a merge view. onClose runs when the user leaves the view;
+// resolved is true if the original was rewritten or the copy deleted.
+func New(onClose func(originalPath string, resolved bool)) *View {
+	v := &View{onClose: onClose}
+	v.list.Axis = layout.Vertical
+	return v
+}
+
+// Open diffs the conflict copy against its original and shows the view.
+// Dropping the copy moves it to trash, or deletes it if trash is nil;
+// conflictRel is its path relative to the vault root.
+func (v *View) Open(vfs fs.VaultFS, trash *fs.Trash, originalPath, conflictPath, conflictRel string) error {
+	original, err := vfs.ReadFile(originalPath)
+	if err != nil {
+		return err
+	}
+	conflict, err := vfs.ReadFile(conflictPath)
+	if err != nil {
+		return err
+	}
+
+	v.fsys = vfs
+	v.trash = trash
+	v.originalPath = originalPath
+	v.conflictPath = conflictPath
+	v.conflictRel = conflictRel
+	v.originalName = originalPath
+	v.originalMod = time.Time{}
+	v.originalSum = sha256.Sum256(original)
+	if info, err := vfs.Stat(originalPath); err == nil {
+		v.originalName = info.Name
+		v.originalMod = info.ModTime
+	}
+	v.chunks = diff.Lines(splitLines(original), splitLines(conflict))
+	v.picks = make([]Pick, len(v.chunks))
+	v.pickClicks = make([][len(pickLabels)]widget.Clickable, len(v.chunks))
+	v.list.Position = layout.Position{}
+	v.err = nil
+	v.active = true
+	return nil
+}
+
+// Active returns true while the view is open
+func (v *View) Active() bool {
+	return v.active
+}
+
+// ConflictPath returns the conflict copy being merged
+func (v *View) ConflictPath() string {
+	if !v.active {
+		return ""
+	}
+	return v.conflictPath
+}
+
+// Close hides the view without touching any file
+func (v *View) Close() {
+	v.active = false
+	v.chunks = nil
+}
+
+// finish closes the view on the user's behalf and reports back
+func (v *View) finish(resolved bool) {
+	original := v.originalPath
+	v.Close()
+	if v.onClose != nil {
+		v.onClose(original, resolved)
+	}
+}
+
+// Merged returns the original with the picked hunks applied
+func (v *View) Merged() []byte {
+	var lines []string
+	for i, c := range v.chunks {
+		switch {
+		case c.Equal, v.picks[i] == PickOriginal:
+			lines = append(lines, c.A...)
+		case v.picks[i] == PickConflict:
+			lines = append(lines, c.B...)
+		default:
+			lines = append(lines, c.A...)
+			lines = append(lines, c.B...)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// apply writes the merge over the original and drops the conflict copy.
+// An original someone else wrote since Open is left alone.
+func (v *View) apply() {
+	if err := v.checkOriginal(); err != nil {
+		v.err = err
+		return
+	}
+	if err := v.fsys.WriteFile(v.originalPath, v.Merged()); err != nil {
+		v.err = err
+		return
+	}
+	v.deleteConflict()
+}
+
+// checkOriginal returns ErrOriginalChanged if the original is not what
+// Open read. A newer mtime with the same content is fine.
+func (v *View) checkOriginal() error {
+	info, err := v.fsys.Stat(v.originalPath)
+	if err != nil {
+		return err
+	}
+	if info.ModTime.Equal(v.originalMod) {
+		return nil
+	}
+	content, err := v.fsys.ReadFile(v.originalPath)
+	if err != nil {
+		return err
+	}
+	if sha256.Sum256(content) != v.originalSum {
+		return ErrOriginalChanged
+	}
+	return nil
+}
+
+// deleteConflict moves the conflict copy to the trash and closes the view
+func (v *View) deleteConflict() {
+	var err error
+	if v.trash != nil {
+		err = v.trash.Put(v.conflictPath, v.conflictRel, false)
+	} else {
+		err = v.fsys.Delete(v.conflictPath)
+	}
+	if err != nil {
+		v.err = err
+		return
+	}
+	v.finish(true)
+}
+
+func splitLines(content []byte) []string {
+	return strings.Split(string(content), "\n")
+}
+
+// Layout renders the header and the diff
+func (v *View) Layout(gtx C, th *material.Theme) D {
+	if !v.active {
+		return D{}
+	}
+
+	for i := range v.pickClicks {
+		for p := range v.pickClicks[i] {
+			if v.pickClicks[i][p].Clicked(gtx) {
+				v.picks[i] = Pick(p)
+			}
+		}
+	}
+	if v.applyClick.Clicked(gtx) {
+		v.apply()
+	}
+	if v.deleteClick.Clicked(gtx) {
+		v.deleteConflict()
+	}
+	if v.closeClick.Clicked(gtx) {
+		v.finish(false)
+	}
+	if !v.active {
+		return D{Size: gtx.Constraints.Min}
+	}
+
+	return layout.Inset{
+		Top:    unit.Dp(16),
+		Left:   unit.Dp(24),
+		Right:  unit.Dp(24),
+		Bottom: unit.Dp(16),
+	}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return v.layoutHeader(gtx, th)
+			}),
+			layout.Flexed(1, func(gtx C) D {
+				return material.List(th, &v.list).Layout(gtx, len(v.chunks), func(gtx C, i int) D {
+					if v.chunks[i].Equal {
+						return v.layoutEqual(gtx, th, i)
+					}
+					return v.layoutHunk(gtx, th, i)
+				})
+			}),
+		)
+	})
+}
+
+func (v *View) layoutHeader(gtx C, th *material.Theme) D {
+	return layout.Inset{Bottom: unit.Dp(12)}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+					layout.Flexed(1, func(gtx C) D {
+						label := material.Body1(th, "Sync conflict: "+v.originalName)
+						label.Color = app.Yellow()
+						label.Font.Weight = font.Bold
+						return label.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						return layoutAction(gtx, th, &v.applyClick, "[Apply merge]", app.Accent())
+					}),
+					layout.Rigid(func(gtx C) D {
+						return layoutAction(gtx, th, &v.deleteClick, "[Delete conflict copy]", app.Red())
+					}),
+					layout.Rigid(func(gtx C) D {
+						return layoutAction(gtx, th, &v.closeClick, "[Close]", app.Comment())
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx C) D {
+				text := "Left: original · Right: conflict copy"
+				if v.err != nil {
+					text = v.err.Error()
+				}
+				label := material.Caption(th, text)
+				label.Color = app.Comment()
+				if v.err != nil {
+					label.Color = app.Red()
+				}
+				return label.Layout(gtx)
+			}),
+		)
+	})
+}
+
+// layoutEqual renders unchanged lines, folding long runs down to the
+// context around neighbouring hunks
+func (v *View) layoutEqual(gtx C, th *material.Theme, i int) D {
+	lines := v.chunks[i].A
+	var head, tail []string
+	hidden := 0
+	switch {
+	case len(v.chunks) == 1:
+		head = lines
+	case i == 0 && len(lines) > contextLines:
+		hidden = len(lines) - contextLines
+		tail = lines[hidden:]
+	case i == len(v.chunks)-1 && len(lines) > contextLines:
+		head = lines[:contextLines]
+		hidden = len(lines) - contextLines
+	case len(lines) > 2*contextLines:
+		head = lines[:contextLines]
+		tail = lines[len(lines)-contextLines:]
+		hidden = len(lines) - 2*contextLines
+	default:
+		head = lines
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return layoutLines(gtx, th, head, app.Comment())
+		}),
+		layout.Rigid(func(gtx C) D {
+			if hidden == 0 {
+				return D{}
+			}
+			label := material.Caption(th, fmt.Sprintf("⋯ %d unchanged lines", hidden))
+			label.Color = app.Comment()
+			return layout.Inset{Top: unit.Dp(2), Bottom: unit.Dp(2)}.Layout(gtx, label.Layout)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layoutLines(gtx, th, tail, app.Comment())
+		}),
+	)
+}
+
+// layoutHunk renders a changed hunk side by side with its pick buttons
+func (v *View) layoutHunk(gtx C, th *material.Theme, i int) D {
+	c := v.chunks[i]
+	pick := v.picks[i]
+	return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{}.Layout(gtx,
+					layout.Flexed(0.5, func(gtx C) D {
+						kept := pick == PickOriginal || pick == PickBoth
+						return layoutSide(gtx, th, c.A, app.Red(), kept)
+					}),
+					layout.Rigid(layout.Spacer{Width: unit.Dp(8)}.Layout),
+					layout.Flexed(0.5, func(gtx C) D {
+						kept := pick == PickConflict || pick == PickBoth
+						return layoutSide(gtx, th, c.B, app.Green(), kept)
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx C) D {
+				children := make([]layout.FlexChild, len(pickLabels))
+				for p := range pickLabels {
+					p := p
+					children[p] = layout.Rigid(func(gtx C) D {
+						col := app.Comment()
+						if Pick(p) == pick {
+							col = app.Accent()
+						}
+						return layoutAction(gtx, th, &v.pickClicks[i][p], "["+pickLabels[p]+"]", col)
+					})
+				}
+				return layout.Flex{}.Layout(gtx, children...)
+			}),
+		)
+	})
+}
+
+// layoutSide renders one side of a hunk on a tinted background. Sides
+// that will not be kept are dimmed.
+func layoutSide(gtx C, th *material.Theme, lines []string, tint color.NRGBA, kept bool) D {
+	alpha := uint8(0x50)
+	fg := app.Foreground()
+	if !kept {
+		alpha = 0x18
+		fg = app.Comment()
+	}
+	tint.A = alpha
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(func(gtx C) D {
+			rect := image.Rectangle{Max: gtx.Constraints.Min}
+			paint.FillShape(gtx.Ops, tint, clip.Rect(rect).Op())
+			return D{Size: gtx.Constraints.Min}
+		}),
+		layout.Stacked(func(gtx C) D {
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+				if len(lines) == 0 {
+					label := material.Caption(th, "(no lines)")
+					label.Color = app.Comment()
+					return label.Layout(gtx)
+				}
+				return layoutLines(gtx, th, lines, fg)
+			})
+		}),
+	)
+}
+
+// layoutLines renders lines in the monospace font
+func layoutLines(gtx C, th *material.Theme, lines []string, col color.NRGBA) D {
+	if len(lines) == 0 {
+		return D{}
+	}
+	label := material.Body2(th, strings.Join(lines, "\n"))
+	label.Font.Typeface = "monospace"
+	label.Color = col
+	return label.Layout(gtx)
+}
+
+// layoutAction renders a bracketed text button
+func layoutAction(gtx C, th *material.Theme, click *widget.Clickable, text string, col color.NRGBA) D {
+	return click.Layout(gtx, func(gtx C) D {
+		return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4), Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+			label := material.Body2(th, text)
+			label.Color = col
+			return label.Layout(gtx)
+		})
+	})
+}
@@ -0,0 +1,97 @@
+package palette
+
+import (
+	"slices"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/widget/material"
+
+	"giopad/action"
+	"giopad/internal/fuzzy"
+	"giopad/ui/picker"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// Palette is the Ctrl+Shift+P overlay that finds actions by title and
+// runs them
+type Palette struct {
+	picker   *picker.Picker
+	registry *action.Registry
+	shown    []*action.Action // actions behind the rows of the last filter
+}
+
+// New creates a hidden palette over the actions in registry
+func New(registry *action.Registry) *Palette {
+	return &Palette{picker: picker.New(), registry: registry}
+}
+
+// Open shows the palette with every action listed
+func (p *Palette) Open() {
+	p.picker.Show("Run command…", p.filter, p.pick)
+}
+
+// Visible returns true while the palette is open
+func (p *Palette) Visible() bool {
+	return p.picker.Visible()
+}
+
+// Close hides the palette
+func (p *Palette) Close() {
+	p.picker.Close()
+}
+
+// Layout renders the palette over the whole window, if visible
+func (p *Palette) Layout(gtx C, th *material.Theme) D {
+	return p.picker.Layout(gtx, th)
+}
+
+func (p *Palette) pick(i int, _ string) {
+	if i < len(p.shown) {
+		p.registry.Run(p.shown[i].ID)
+	}
+}
+
+func (p *Palette) filter(query string) []picker.Item {
+	query = strings.TrimSpace(query)
+	type hit struct {
+		a     *action.Action
+		score int
+		pos   []int
+	}
+	var hits []hit
+	for _, a := range p.registry.All() {
+		if a.Hidden || !a.Enabled() {
+			continue
+		}
+		score, pos, ok := fuzzy.Match(query, a.Title)
+		if !ok {
+			// Let people who know the ID type it
+			if _, _, ok = fuzzy.Match(query, a.ID); !ok {
+				continue
+			}
+		}
+		hits = append(hits, hit{a, score, pos})
+	}
+	if query != "" {
+		slices.SortStableFunc(hits, func(a, b hit) int {
+			return b.score - a.score
+		})
+	}
+
+	p.shown = p.shown[:0]
+	items := make([]picker.Item, 0, len(hits))
+	for _, h := range hits {
+		p.shown = append(p.shown, h.a)
+		item := picker.Item{Title: h.a.Title, Matches: h.pos}
+		if keys := p.registry.KeysFor(h.a.ID); len(keys) > 0 {
+			item.Hint = keys[0].String()
+		}
+		items = append(items, item)
+	}
+	return items
+}
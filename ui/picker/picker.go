@@ -0,0 +1,332 @@
+package picker
+
+import (
+	"image"
+	"image/color"
+	"unicode/utf8"
+
+	"gioui.org/font"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/styledtext"
+
+	"giopad/app"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// Item is one row of the picker
+type Item struct {
+	Title   string
+	Detail  string // Dimmed text after the title, e.g. the folder
+	Hint    string // Right-aligned text, e.g. a key binding
+	Matches []int  // Byte offsets of Title runes to highlight
+}
+
+// Picker is a keyboard-driven overlay: a query field over a list of items
+// that a filter function recomputes as the query changes. Up/Down move the
+// highlight, Enter picks and Escape or a click outside closes it.
+type Picker struct {
+	hint   string
+	filter func(query string) []Item
+	onPick func(index int, query string)
+
+	items     []Item
+	query     string
+	highlight int
+
+	input        widget.Editor
+	clicks       []widget.Clickable
+	list         widget.List
+	visible      bool
+	requestFocus bool
+	scrim        int // pointer tag for the scrim
+	card         int // pointer tag for the card
+}
+
+// New creates a hidden Picker
+func New() *Picker {
+	p := &Picker{}
+	p.input.SingleLine = true
+	p.input.Submit = true
+	p.list.Axis = layout.Vertical
+	return p
+}
+
+// Show opens the picker with an empty query. filter returns the items for
+// a query; onPick receives the index of the chosen item in the last
+// filter result, along with the query.
+func (p *Picker) Show(hint string, filter func(query string) []Item, onPick func(index int, query string)) {
+	p.hint = hint
+	p.filter = filter
+	p.onPick = onPick
+	p.input.SetText("")
+	p.setQuery("")
+	p.visible = true
+	p.requestFocus = true
+}
+
+// Visible returns true while the picker is open
+func (p *Picker) Visible() bool {
+	return p.visible
+}
+
+// Close hides the picker without picking anything
+func (p *Picker) Close() {
+	p.visible = false
+}
+
+func (p *Picker) setQuery(query string) {
+	p.query = query
+	p.items = p.filter(query)
+	p.highlight = 0
+	p.list.Position = layout.Position{}
+	if len(p.clicks) < len(p.items) {
+		p.clicks = make([]widget.Clickable, len(p.items))
+	}
+}
+
+func (p *Picker) pick(i int) {
+	p.visible = false
+	if i >= 0 && i < len(p.items) && p.onPick != nil {
+		p.onPick(i, p.query)
+	}
+}
+
+func (p *Picker) move(delta int) {
+	if len(p.items) == 0 {
+		return
+	}
+	p.highlight = (p.highlight + delta + len(p.items)) % len(p.items)
+	p.list.ScrollTo(p.highlight)
+}
+
+// Layout renders the picker over the whole window, if visible
+func (p *Picker) Layout(gtx C, th *material.Theme) D {
+	if !p.visible {
+		return D{}
+	}
+
+	for i := range p.items {
+		if p.clicks[i].Clicked(gtx) {
+			p.pick(i)
+			return D{}
+		}
+	}
+
+	for {
+		ev, ok := p.input.Update(gtx)
+		if !ok {
+			break
+		}
+		switch ev.(type) {
+		case widget.ChangeEvent:
+			if text := p.input.Text(); text != p.query {
+				p.setQuery(text)
+			}
+		case widget.SubmitEvent:
+			p.pick(p.highlight)
+			return D{}
+		}
+	}
+
+	for {
+		ev, ok := gtx.Event(
+			key.Filter{Focus: &p.input, Name: key.NameEscape},
+			key.Filter{Focus: &p.input, Name: key.NameUpArrow},
+			key.Filter{Focus: &p.input, Name: key.NameDownArrow},
+			key.Filter{Focus: &p.input, Name: key.NamePageUp},
+			key.Filter{Focus: &p.input, Name: key.NamePageDown},
+			key.Filter{Focus: &p.input, Name: "N", Required: key.ModCtrl},
+			key.Filter{Focus: &p.input, Name: "P", Required: key.ModCtrl},
+		)
+		if !ok {
+			break
+		}
+		e, ok := ev.(key.Event)
+		if !ok || e.State != key.Press {
+			continue
+		}
+		switch e.Name {
+		case key.NameEscape:
+			p.Close()
+			return D{}
+		case key.NameUpArrow, "P":
+			p.move(-1)
+		case key.NameDownArrow, "N":
+			p.move(1)
+		case key.NamePageUp:
+			p.move(-min(10, p.highlight))
+		case key.NamePageDown:
+			p.move(min(10, len(p.items)-1-p.highlight))
+		}
+	}
+
+	// Swallow pointer events aimed at the widgets underneath; a press
+	// outside the card closes the picker
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target:  &p.scrim,
+			Kinds:   pointer.Press | pointer.Release | pointer.Drag | pointer.Scroll,
+			ScrollX: pointer.ScrollRange{Min: -1 << 30, Max: 1 << 30},
+			ScrollY: pointer.ScrollRange{Min: -1 << 30, Max: 1 << 30},
+		})
+		if !ok {
+			break
+		}
+		if e, ok := ev.(pointer.Event); ok && e.Kind == pointer.Press {
+			p.Close()
+			return D{}
+		}
+	}
+
+	for {
+		if _, ok := gtx.Event(pointer.Filter{Target: &p.card, Kinds: pointer.Press}); !ok {
+			break
+		}
+	}
+
+	size := gtx.Constraints.Max
+	area := clip.Rect(image.Rectangle{Max: size}).Push(gtx.Ops)
+	paint.ColorOp{Color: color.NRGBA{A: 0x80}}.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	event.Op(gtx.Ops, &p.scrim)
+	area.Pop()
+
+	if p.requestFocus {
+		gtx.Execute(key.FocusCmd{Tag: &p.input})
+		p.requestFocus = false
+	}
+
+	// Card near the top, like a dropdown from the title bar
+	gtx.Constraints.Min = image.Point{}
+	layout.N.Layout(gtx, func(gtx C) D {
+		return layout.Inset{Top: unit.Dp(48), Left: unit.Dp(16), Right: unit.Dp(16)}.Layout(gtx, func(gtx C) D {
+			if maxW := gtx.Dp(unit.Dp(560)); gtx.Constraints.Max.X > maxW {
+				gtx.Constraints.Max.X = maxW
+			}
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			gtx.Constraints.Max.Y = gtx.Constraints.Max.Y * 2 / 3
+			return p.layoutCard(gtx, th)
+		})
+	})
+	return D{Size: size}
+}
+
+func (p *Picker) layoutCard(gtx C, th *material.Theme) D {
+	return layout.Stack{}.Layout(gtx,
+		// Card background
+		layout.Expanded(func(gtx C) D {
+			rect := image.Rectangle{Max: gtx.Constraints.Min}
+			paint.FillShape(gtx.Ops, app.Surface(), clip.UniformRRect(rect, gtx.Dp(unit.Dp(6))).Op(gtx.Ops))
+			// Keep presses on the card from reaching the scrim
+			defer clip.Rect(rect).Push(gtx.Ops).Pop()
+			event.Op(gtx.Ops, &p.card)
+			return D{Size: gtx.Constraints.Min}
+		}),
+		// Content
+		layout.Stacked(func(gtx C) D {
+			return layout.UniformInset(unit.Dp(12)).Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx C) D {
+						ed := material.Editor(th, &p.input, p.hint)
+						ed.Color = app.Foreground()
+						ed.HintColor = app.Comment()
+						ed.TextSize = unit.Sp(14)
+						return ed.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						if len(p.items) == 0 {
+							return D{}
+						}
+						return layout.Inset{Top: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+							return material.List(th, &p.list).Layout(gtx, len(p.items), func(gtx C, i int) D {
+								return p.layoutItem(gtx, th, i)
+							})
+						})
+					}),
+				)
+			})
+		}),
+	)
+}
+
+func (p *Picker) layoutItem(gtx C, th *material.Theme, i int) D {
+	item := p.items[i]
+	return p.clicks[i].Layout(gtx, func(gtx C) D {
+		return layout.Stack{}.Layout(gtx,
+			layout.Expanded(func(gtx C) D {
+				if i == p.highlight {
+					rect := image.Rectangle{Max: gtx.Constraints.Min}
+					paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(rect).Op())
+				}
+				return D{Size: gtx.Constraints.Min}
+			}),
+			layout.Stacked(func(gtx C) D {
+				gtx.Constraints.Min.X = gtx.Constraints.Max.X
+				return layout.Inset{
+					Top:    unit.Dp(5),
+					Bottom: unit.Dp(5),
+					Left:   unit.Dp(8),
+					Right:  unit.Dp(8),
+				}.Layout(gtx, func(gtx C) D {
+					return layout.Flex{Alignment: layout.Baseline}.Layout(gtx,
+						layout.Flexed(1, func(gtx C) D {
+							return layoutTitle(gtx, th, item)
+						}),
+						layout.Rigid(func(gtx C) D {
+							if item.Hint == "" {
+								return D{}
+							}
+							return layout.Inset{Left: unit.Dp(12)}.Layout(gtx, func(gtx C) D {
+								label := material.Caption(th, item.Hint)
+								label.Color = app.Comment()
+								return label.Layout(gtx)
+							})
+						}),
+					)
+				})
+			}),
+		)
+	})
+}
+
+// layoutTitle renders the title with its matched runes highlighted,
+// followed by the detail text
+func layoutTitle(gtx C, th *material.Theme, item Item) D {
+	size := unit.Sp(14)
+	plain := font.Font{}
+	bold := font.Font{Weight: font.Bold}
+	var spans []styledtext.SpanStyle
+	add := func(text string, col color.NRGBA, f font.Font) {
+		if text != "" {
+			spans = append(spans, styledtext.SpanStyle{Content: text, Size: size, Color: col, Font: f})
+		}
+	}
+
+	pos := 0
+	for _, m := range item.Matches {
+		if m < pos || m >= len(item.Title) {
+			continue
+		}
+		_, n := utf8.DecodeRuneInString(item.Title[m:])
+		add(item.Title[pos:m], app.Foreground(), plain)
+		add(item.Title[m:m+n], app.Yellow(), bold)
+		pos = m + n
+	}
+	add(item.Title[pos:], app.Foreground(), plain)
+	if item.Detail != "" {
+		spans = append(spans, styledtext.SpanStyle{Content: "  " + item.Detail, Size: unit.Sp(12), Color: app.Comment(), Font: plain})
+	}
+	return styledtext.Text(th.Shaper, spans...).Layout(gtx, nil)
+}
@@ -0,0 +1,307 @@
+package searchpanel
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+
+	"gioui.org/font"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/styledtext"
+
+	"giopad/app"
+	"giopad/search"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// maxResults caps how many notes a query lists
+const maxResults = 100
+
+// resultClicks holds the buttons of one result: its title and its lines
+type resultClicks struct {
+	title widget.Clickable
+	lines []widget.Clickable
+}
+
+// Panel is the side panel for full-text search across the vault
+type Panel struct {
+	indexer *search.Indexer
+	input   widget.Editor
+	query   string
+	gen     uint64
+	results []search.Result
+	clicks  []resultClicks
+	list    widget.List
+	active  bool
+	focus   bool
+
+	closeClick widget.Clickable
+
+	onOpen func(path string, line int)
+}
+
+// New creates a search panel. onOpen runs when a result is clicked, with
+// the 0-based line to show.
+func New(onOpen func(path string, line int)) *Panel {
+	p := &Panel{onOpen: onOpen}
+	p.input.SingleLine = true
+	p.input.Submit = true
+	p.list.Axis = layout.Vertical
+	return p
+}
+
+// SetIndexer sets the vault index queries run against
+func (p *Panel) SetIndexer(x *search.Indexer) {
+	p.indexer = x
+	p.gen = 0
+	p.results = nil
+}
+
+// Open shows the panel and focuses the query field
+func (p *Panel) Open() {
+	p.active = true
+	p.focus = true
+	p.input.SetCaret(p.input.Len(), 0) // Select the last query for retyping
+}
+
+// Close hides the panel, keeping the query for next time
+func (p *Panel) Close() {
+	p.active = false
+}
+
+// Active returns true while the panel is shown
+func (p *Panel) Active() bool {
+	return p.active
+}
+
+// run queries the index if the query or the index changed since last time
+func (p *Panel) run() {
+	if p.indexer == nil {
+		return
+	}
+	query := p.input.Text()
+	gen := p.indexer.Index.Generation()
+	if query == p.query && gen == p.gen {
+		return
+	}
+	p.query, p.gen = query, gen
+	p.results = p.indexer.Index.Search(query, maxResults)
+	if len(p.clicks) < len(p.results) {
+		p.clicks = append(p.clicks, make([]resultClicks, len(p.results)-len(p.clicks))...)
+	}
+	for i, r := range p.results {
+		if len(p.clicks[i].lines) < len(r.Matches) {
+			p.clicks[i].lines = make([]widget.Clickable, len(r.Matches))
+		}
+	}
+}
+
+func (p *Panel) open(path string, line int) {
+	if p.onOpen != nil {
+		p.onOpen(path, line)
+	}
+}
+
+// Layout renders the query field and the results
+func (p *Panel) Layout(gtx C, th *material.Theme) D {
+	if !p.active {
+		return D{}
+	}
+
+	if p.closeClick.Clicked(gtx) {
+		p.Close()
+		return D{}
+	}
+	for {
+		ev, ok := gtx.Event(key.Filter{Focus: &p.input, Name: key.NameEscape})
+		if !ok {
+			break
+		}
+		if e, ok := ev.(key.Event); ok && e.State == key.Press {
+			p.Close()
+			return D{}
+		}
+	}
+	for {
+		ev, ok := p.input.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := ev.(widget.SubmitEvent); ok && len(p.results) > 0 {
+			// Enter opens the best match
+			r := p.results[0]
+			line := 0
+			if len(r.Matches) > 0 {
+				line = r.Matches[0].Line
+			}
+			p.open(r.Path, line)
+		}
+	}
+	p.run()
+	for i, r := range p.results {
+		if p.clicks[i].title.Clicked(gtx) {
+			line := 0
+			if len(r.Matches) > 0 {
+				line = r.Matches[0].Line
+			}
+			p.open(r.Path, line)
+		}
+		for j, m := range r.Matches {
+			if p.clicks[i].lines[j].Clicked(gtx) {
+				p.open(r.Path, m.Line)
+			}
+		}
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return p.layoutHeader(gtx, th)
+		}),
+		layout.Flexed(1, func(gtx C) D {
+			return material.List(th, &p.list).Layout(gtx, len(p.results), func(gtx C, i int) D {
+				return p.layoutResult(gtx, th, i)
+			})
+		}),
+	)
+}
+
+func (p *Panel) layoutHeader(gtx C, th *material.Theme) D {
+	return layout.Inset{Bottom: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+					layout.Flexed(1, func(gtx C) D {
+						label := material.Body1(th, "Search")
+						label.Color = app.Foreground()
+						label.Font.Weight = font.Bold
+						return label.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						return layoutAction(gtx, th, &p.closeClick, "[Close]", app.Comment())
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx C) D {
+				if p.focus {
+					gtx.Execute(key.FocusCmd{Tag: &p.input})
+					p.focus = false
+				}
+				return layout.Inset{Top: unit.Dp(6), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx C) D {
+					ed := material.Editor(th, &p.input, "Search notes...")
+					ed.Color = app.Foreground()
+					ed.HintColor = app.Comment()
+					ed.TextSize = unit.Sp(13)
+					return ed.Layout(gtx)
+				})
+			}),
+			layout.Rigid(func(gtx C) D {
+				label := material.Caption(th, p.status())
+				label.Color = app.Comment()
+				return label.Layout(gtx)
+			}),
+		)
+	})
+}
+
+// status describes the index and the last query
+func (p *Panel) status() string {
+	switch {
+	case p.indexer == nil:
+		return "No vault loaded"
+	case p.indexer.Busy():
+		return fmt.Sprintf("Indexing… %d notes so far", p.indexer.Index.Len())
+	case len(p.results) == maxResults:
+		return fmt.Sprintf("First %d matching notes", maxResults)
+	case len(p.results) > 0:
+		return fmt.Sprintf("%d matching notes", len(p.results))
+	case p.query != "":
+		return "No matches"
+	default:
+		return fmt.Sprintf("%d notes indexed", p.indexer.Index.Len())
+	}
+}
+
+// layoutResult renders a note title with its matching lines below
+func (p *Panel) layoutResult(gtx C, th *material.Theme, i int) D {
+	r := p.results[i]
+	clicks := &p.clicks[i]
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx C) D {
+			return clicks.title.Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx C) D {
+						label := material.Body2(th, r.Name)
+						label.Color = app.Blue()
+						label.MaxLines = 1
+						return label.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						text := r.Rel
+						if more := r.Lines - len(r.Matches); more > 0 {
+							text += fmt.Sprintf(" · %d more lines", more)
+						}
+						label := material.Caption(th, text)
+						label.Color = app.Comment()
+						label.MaxLines = 1
+						return label.Layout(gtx)
+					}),
+				)
+			})
+		}),
+	}
+	for j := range r.Matches {
+		m := r.Matches[j]
+		click := &clicks.lines[j]
+		children = append(children, layout.Rigid(func(gtx C) D {
+			return click.Layout(gtx, func(gtx C) D {
+				return layout.Inset{Top: unit.Dp(2), Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+					return layoutMatch(gtx, th, m)
+				})
+			})
+		}))
+	}
+	return layout.Inset{Top: unit.Dp(6), Bottom: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+}
+
+// layoutMatch renders a line number and the line with its hits highlighted
+func layoutMatch(gtx C, th *material.Theme, m search.Match) D {
+	size := unit.Sp(12)
+	plain := font.Font{}
+	bold := font.Font{Weight: font.Bold}
+	spans := []styledtext.SpanStyle{
+		{Content: strconv.Itoa(m.Line+1) + ": ", Size: size, Color: app.Comment(), Font: plain},
+	}
+	pos := 0
+	for _, s := range m.Spans {
+		if s.Start > pos {
+			spans = append(spans, styledtext.SpanStyle{Content: m.Text[pos:s.Start], Size: size, Color: app.Foreground(), Font: plain})
+		}
+		spans = append(spans, styledtext.SpanStyle{Content: m.Text[s.Start:s.End], Size: size, Color: app.Yellow(), Font: bold})
+		pos = s.End
+	}
+	if pos < len(m.Text) {
+		spans = append(spans, styledtext.SpanStyle{Content: m.Text[pos:], Size: size, Color: app.Foreground(), Font: plain})
+	}
+	return styledtext.Text(th.Shaper, spans...).Layout(gtx, nil)
+}
+
+// layoutAction renders a bracketed text button
+func layoutAction(gtx C, th *material.Theme, click *widget.Clickable, text string, col color.NRGBA) D {
+	return click.Layout(gtx, func(gtx C) D {
+		return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4), Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+			label := material.Body2(th, text)
+			label.Color = col
+			return label.Layout(gtx)
+		})
+	})
+}
@@ -0,0 +1,241 @@
+package switcher
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/widget/material"
+
+	"giopad/fs"
+	"giopad/internal/fuzzy"
+	"giopad/search"
+	"giopad/ui/picker"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+const (
+	maxRecent = 50  // recently opened notes to remember
+	maxItems  = 100 // rows to list for one query
+	nameBonus = 8   // a match in the file name beats one spread over folders
+)
+
+// Switcher is the Ctrl+P overlay that opens notes by fuzzy-matching their
+// vault-relative paths. Recently opened notes rank higher.
+type Switcher struct {
+	picker *picker.Picker
+	recent []string // paths, most recent first
+	notes  []search.Note
+	shown  []search.Note // notes behind the rows of the last filter
+
+	onOpen   func(path string)
+	onCreate func(rel string)
+}
+
+// New creates a hidden switcher. onOpen runs with the path of the chosen
+// note; onCreate with a vault-relative name when the query matched nothing.
+func New(onOpen func(path string), onCreate func(rel string)) *Switcher {
+	return &Switcher{
+		picker:   picker.New(),
+		onOpen:   onOpen,
+		onCreate: onCreate,
+	}
+}
+
+// Visited moves path to the front of the recent list
+func (s *Switcher) Visited(path string) {
+	if path == "" {
+		return
+	}
+	s.recent = slices.DeleteFunc(s.recent, func(p string) bool { return p == path })
+	s.recent = slices.Insert(s.recent, 0, path)
+	if len(s.recent) > maxRecent {
+		s.recent = s.recent[:maxRecent]
+	}
+}
+
+// Recent returns the recently opened notes, most recent first
+func (s *Switcher) Recent() []string {
+	return s.recent
+}
+
+// SetRecent replaces the recent list, e.g. with one saved last session
+func (s *Switcher) SetRecent(paths []string) {
+	s.recent = slices.Clone(paths)
+	if len(s.recent) > maxRecent {
+		s.recent = s.recent[:maxRecent]
+	}
+}
+
+// Open shows the switcher over notes, usually from Notes
+func (s *Switcher) Open(notes []search.Note) {
+	s.notes = notes
+	s.picker.Show("Go to note…", s.filter, s.pick)
+}
+
+// Visible returns true while the switcher is open
+func (s *Switcher) Visible() bool {
+	return s.picker.Visible()
+}
+
+// Close hides the switcher
+func (s *Switcher) Close() {
+	s.picker.Close()
+}
+
+// Layout renders the switcher over the whole window, if visible
+func (s *Switcher) Layout(gtx C, th *material.Theme) D {
+	return s.picker.Layout(gtx, th)
+}
+
+func (s *Switcher) pick(i int, query string) {
+	if i < len(s.shown) {
+		if s.onOpen != nil {
+			s.onOpen(s.shown[i].Path)
+		}
+		return
+	}
+	if rel := newNoteName(query); rel != "" && s.onCreate != nil {
+		s.onCreate(rel)
+	}
+}
+
+// candidate is a note with its score for the current query
+type candidate struct {
+	note  search.Note
+	score int
+	pos   []int // matched byte offsets in note.Name
+}
+
+func (s *Switcher) filter(query string) []picker.Item {
+	query = strings.TrimSpace(query)
+	rank := make(map[string]int, len(s.recent))
+	for i, p := range s.recent {
+		rank[p] = maxRecent - i
+	}
+
+	var found []candidate
+	for _, n := range s.notes {
+		c, ok := match(query, n)
+		if !ok {
+			continue
+		}
+		c.score += rank[n.Path]
+		found = append(found, c)
+	}
+	slices.SortFunc(found, func(a, b candidate) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		if len(a.note.Rel) != len(b.note.Rel) {
+			return len(a.note.Rel) - len(b.note.Rel)
+		}
+		return strings.Compare(a.note.Rel, b.note.Rel)
+	})
+	if len(found) > maxItems {
+		found = found[:maxItems]
+	}
+
+	s.shown = s.shown[:0]
+	items := make([]picker.Item, 0, len(found)+1)
+	for _, c := range found {
+		s.shown = append(s.shown, c.note)
+		item := picker.Item{Title: c.note.Name, Matches: c.pos}
+		if dir := path.Dir(c.note.Rel); dir != "." {
+			item.Detail = dir
+		}
+		if _, ok := rank[c.note.Path]; ok && query == "" {
+			item.Hint = "recent"
+		}
+		items = append(items, item)
+	}
+	if len(found) == 0 {
+		if rel := newNoteName(query); rel != "" {
+			items = append(items, picker.Item{Title: "Create " + rel, Hint: "Enter"})
+		}
+	}
+	return items
+}
+
+// match scores note against query. A hit inside the file name is preferred;
+// otherwise the whole relative path is tried and only the matches that land
+// in the name are highlighted.
+func match(query string, n search.Note) (candidate, bool) {
+	c := candidate{note: n}
+	if query == "" {
+		return c, true
+	}
+	nameScore, namePos, nameOK := fuzzy.Match(query, n.Name)
+	relScore, relPos, relOK := fuzzy.Match(query, n.Rel)
+	switch {
+	case nameOK && nameScore+nameBonus >= relScore:
+		c.score, c.pos = nameScore+nameBonus, namePos
+	case relOK:
+		c.score = relScore
+		offset := len(n.Rel) - len(n.Name)
+		for _, p := range relPos {
+			if p >= offset {
+				c.pos = append(c.pos, p-offset)
+			}
+		}
+	default:
+		return c, false
+	}
+	return c, true
+}
+
+// newNoteName turns a query into the relative name of a note to create,
+// or "" if it cannot name one
+func newNoteName(query string) string {
+	rel := strings.Trim(strings.TrimSpace(query), "/")
+	if rel == "" {
+		return ""
+	}
+	for _, name := range strings.Split(rel, "/") {
+		if name == "" || name == "." || name == ".." || strings.HasPrefix(name, ".") {
+			return ""
+		}
+	}
+	if !fs.IsMarkdown(rel) {
+		rel += ".md"
+	}
+	return rel
+}
+
+// Notes lists every note the switcher can offer: the ones in the search
+// index, plus any in the loaded part of the tree the index has not reached
+func Notes(root *fs.Node, index *search.Index) []search.Note {
+	var notes []search.Note
+	seen := make(map[string]bool)
+	if index != nil {
+		for _, n := range index.Notes() {
+			if _, conflict := fs.ParseConflictName(n.Name); conflict {
+				continue
+			}
+			seen[n.Path] = true
+			notes = append(notes, n)
+		}
+	}
+	var walk func(node *fs.Node, prefix string)
+	walk = func(node *fs.Node, prefix string) {
+		for _, child := range node.Children {
+			rel := prefix + child.Name
+			switch {
+			case child.IsDir:
+				walk(child, rel+"/")
+			case !seen[child.Path] && fs.IsMarkdown(child.Name):
+				seen[child.Path] = true
+				notes = append(notes, search.Note{Path: child.Path, Name: child.Name, Rel: rel})
+			}
+		}
+	}
+	if root != nil {
+		walk(root, "")
+	}
+	return notes
+}
@@ -0,0 +1,389 @@
+package tabs
+
+import (
+	"image"
+	"path/filepath"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+
+	"giopad/app"
+	"giopad/fs"
+	"giopad/links"
+	"giopad/ui/editor"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// dragSlop is how far a press moves before it drags the tab
+const dragSlop = 8 // dp
+
+// span is where a tab sat in the strip at the last layout, before scrolling
+type span struct {
+	start, end int
+	close      int // start of the close button
+}
+
+// press is a pointer press on a tab that has not been released yet
+type press struct {
+	index    int
+	buttons  pointer.Buttons
+	x        float32
+	onClose  bool
+	dragging bool
+}
+
+// Tabs is the strip of open notes above the content area. Every tab is an
+// editor.Editor of its own, so each keeps its buffer, undo history, dirty
+// flag, mode and scroll position. There is always at least one tab; it may
+// have no note loaded.
+type Tabs struct {
+	// OnActivate runs when the user picks another tab
+	OnActivate func(ed *editor.Editor)
+	// OnClose runs when the user asks to close a tab with a middle-click
+	// or its close button; the caller settles unsaved changes and then
+	// calls Close
+	OnClose func(i int)
+	// OnLink runs when a link in any tab is clicked; see editor.Editor
+	OnLink func(t links.Target, ok bool)
+	// Links returns the notes links resolve against; see editor.Editor
+	Links func() *links.Notes
+	// Invalidate redraws the window; see editor.Editor
+	Invalidate func()
+
+	tabs   []*editor.Editor
+	active int
+	fsys   fs.VaultFS
+	root   string
+
+	spans  []span
+	scroll int
+	reveal bool // scroll the active tab into view at the next layout
+	press  *press
+	strip  int // pointer tag for the strip
+}
+
+// New creates a strip with one empty tab
+func New() *Tabs {
+	t := &Tabs{fsys: fs.OSFS{}}
+	t.tabs = []*editor.Editor{t.newEditor()}
+	return t
+}
+
+func (t *Tabs) newEditor() *editor.Editor {
+	ed := editor.New()
+	ed.SetFS(t.fsys, t.root)
+	ed.OnLink = func(target links.Target, ok bool) {
+		if t.OnLink != nil {
+			t.OnLink(target, ok)
+		}
+	}
+	ed.Links = func() *links.Notes {
+		if t.Links != nil {
+			return t.Links()
+		}
+		return nil
+	}
+	ed.Invalidate = func() {
+		if t.Invalidate != nil {
+			t.Invalidate()
+		}
+	}
+	return ed
+}
+
+// SetFS sets the filesystem and vault root of every tab, and of tabs
+// opened later
+func (t *Tabs) SetFS(vfs fs.VaultFS, root string) {
+	t.fsys, t.root = vfs, root
+	for _, ed := range t.tabs {
+		ed.SetFS(vfs, root)
+	}
+}
+
+// Active returns the editor of the active tab
+func (t *Tabs) Active() *editor.Editor {
+	return t.tabs[t.active]
+}
+
+// ActiveIndex returns the position of the active tab
+func (t *Tabs) ActiveIndex() int {
+	return t.active
+}
+
+// Editors returns the editors of all tabs, left to right
+func (t *Tabs) Editors() []*editor.Editor {
+	return t.tabs
+}
+
+// Len returns the number of tabs
+func (t *Tabs) Len() int {
+	return len(t.tabs)
+}
+
+// Find returns the tab that has path open, or -1
+func (t *Tabs) Find(path string) int {
+	for i, ed := range t.tabs {
+		if path != "" && ed.CurrentPath() == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// Paths returns the notes open in the tabs, left to right
+func (t *Tabs) Paths() []string {
+	var paths []string
+	for _, ed := range t.tabs {
+		if p := ed.CurrentPath(); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// Add opens an empty tab right of the active one and activates it
+func (t *Tabs) Add() *editor.Editor {
+	ed := t.newEditor()
+	t.active++
+	t.tabs = append(t.tabs[:t.active], append([]*editor.Editor{ed}, t.tabs[t.active:]...)...)
+	t.reveal = true
+	return ed
+}
+
+// Activate makes tab i the active one
+func (t *Tabs) Activate(i int) {
+	if i >= 0 && i < len(t.tabs) {
+		t.active = i
+		t.reveal = true
+	}
+}
+
+// Cycle activates the tab delta places away, wrapping around
+func (t *Tabs) Cycle(delta int) {
+	n := len(t.tabs)
+	t.Activate(((t.active+delta)%n + n) % n)
+}
+
+// Close drops tab i, unsaved changes and all. Closing the last tab leaves
+// an empty one.
+func (t *Tabs) Close(i int) {
+	if i < 0 || i >= len(t.tabs) {
+		return
+	}
+	if len(t.tabs) == 1 {
+		t.tabs[0].Close()
+		return
+	}
+	t.tabs = append(t.tabs[:i], t.tabs[i+1:]...)
+	if t.active > i || t.active == len(t.tabs) {
+		t.active--
+	}
+	t.reveal = true
+}
+
+// CloseAll drops every tab, leaving one empty tab
+func (t *Tabs) CloseAll() {
+	t.tabs = []*editor.Editor{t.newEditor()}
+	t.active = 0
+	t.scroll = 0
+}
+
+// Move puts tab from at position to, keeping the same tab active
+func (t *Tabs) Move(from, to int) {
+	if from == to || from < 0 || to < 0 || from >= len(t.tabs) || to >= len(t.tabs) {
+		return
+	}
+	activeEd := t.tabs[t.active]
+	ed := t.tabs[from]
+	t.tabs = append(t.tabs[:from], t.tabs[from+1:]...)
+	t.tabs = append(t.tabs[:to], append([]*editor.Editor{ed}, t.tabs[to:]...)...)
+	for i, e := range t.tabs {
+		if e == activeEd {
+			t.active = i
+		}
+	}
+}
+
+// hit returns the tab under x, in strip coordinates, or -1
+func (t *Tabs) hit(x int) int {
+	for i, s := range t.spans {
+		if x >= s.start && x < s.end {
+			return i
+		}
+	}
+	return -1
+}
+
+// handlePointer activates, closes, drags and scrolls tabs
+func (t *Tabs) handlePointer(gtx C) {
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target:  &t.strip,
+			Kinds:   pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel | pointer.Scroll,
+			ScrollX: pointer.ScrollRange{Min: -1 << 30, Max: 1 << 30},
+			ScrollY: pointer.ScrollRange{Min: -1 << 30, Max: 1 << 30},
+		})
+		if !ok {
+			break
+		}
+		e, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+		x := int(e.Position.X) + t.scroll
+		switch e.Kind {
+		case pointer.Press:
+			i := t.hit(x)
+			if i < 0 {
+				t.press = nil
+				continue
+			}
+			t.press = &press{index: i, buttons: e.Buttons, x: e.Position.X, onClose: x >= t.spans[i].close}
+			if e.Buttons == pointer.ButtonPrimary && !t.press.onClose && i != t.active {
+				t.Activate(i)
+				if t.OnActivate != nil {
+					t.OnActivate(t.Active())
+				}
+			}
+		case pointer.Drag:
+			p := t.press
+			if p == nil || p.buttons != pointer.ButtonPrimary || p.onClose {
+				continue
+			}
+			if !p.dragging {
+				dx := e.Position.X - p.x
+				p.dragging = dx > float32(gtx.Dp(dragSlop)) || -dx > float32(gtx.Dp(dragSlop))
+			}
+			if i := t.hit(x); p.dragging && i >= 0 && i != p.index {
+				t.Move(p.index, i)
+				p.index = i
+			}
+		case pointer.Release:
+			p := t.press
+			t.press = nil
+			if p == nil || p.dragging || t.hit(x) != p.index || t.OnClose == nil {
+				continue
+			}
+			switch {
+			case p.buttons == pointer.ButtonTertiary:
+				t.OnClose(p.index)
+			case p.buttons == pointer.ButtonPrimary && p.onClose && x >= t.spans[p.index].close:
+				t.OnClose(p.index)
+			}
+		case pointer.Cancel:
+			t.press = nil
+		case pointer.Scroll:
+			t.scroll += int(e.Scroll.X + e.Scroll.Y)
+		}
+	}
+}
+
+// Layout renders the tab strip across the available width
+func (t *Tabs) Layout(gtx C, th *material.Theme) D {
+	t.handlePointer(gtx)
+
+	// Lay the tabs out off-screen first to learn their widths
+	calls := make([]op.CallOp, len(t.tabs))
+	t.spans = t.spans[:0]
+	x, height := 0, 0
+	tabGtx := gtx
+	tabGtx.Constraints.Min = image.Point{}
+	for i := range t.tabs {
+		m := op.Record(gtx.Ops)
+		dims, closeW := t.layoutTab(tabGtx, th, i)
+		calls[i] = m.Stop()
+		t.spans = append(t.spans, span{start: x, end: x + dims.Size.X, close: x + dims.Size.X - closeW})
+		x += dims.Size.X
+		height = max(height, dims.Size.Y)
+	}
+	width := gtx.Constraints.Max.X
+	if t.reveal {
+		t.reveal = false
+		s := t.spans[t.active]
+		if s.start < t.scroll {
+			t.scroll = s.start
+		} else if s.end > t.scroll+width {
+			t.scroll = s.end - width
+		}
+	}
+	t.scroll = min(max(t.scroll, 0), max(x-width, 0))
+
+	size := image.Pt(width, height)
+	defer clip.Rect(image.Rectangle{Max: size}).Push(gtx.Ops).Pop()
+	paint.ColorOp{Color: app.Background()}.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	for i, call := range calls {
+		off := op.Offset(image.Pt(t.spans[i].start-t.scroll, 0)).Push(gtx.Ops)
+		call.Add(gtx.Ops)
+		off.Pop()
+	}
+	event.Op(gtx.Ops, &t.strip)
+	return D{Size: size}
+}
+
+// layoutTab renders one tab. Returns its size and the width of its close
+// button, which sits at the right end.
+func (t *Tabs) layoutTab(gtx C, th *material.Theme, i int) (D, int) {
+	ed := t.tabs[i]
+	name := "Untitled"
+	if p := ed.CurrentPath(); p != "" {
+		name = filepath.Base(p)
+	}
+	closeText, dirty := "×", ed.IsDirty()
+	if dirty {
+		closeText = "●" // Like other editors: a dot until saved
+	}
+
+	var closeW int
+	inset := layout.Inset{Top: unit.Dp(6), Bottom: unit.Dp(6), Left: unit.Dp(12), Right: unit.Dp(8)}
+	m := op.Record(gtx.Ops)
+	dims := inset.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				gtx.Constraints.Max.X = min(gtx.Constraints.Max.X, gtx.Dp(unit.Dp(180)))
+				label := material.Body2(th, name)
+				label.MaxLines = 1
+				label.Color = app.Comment()
+				if i == t.active {
+					label.Color = app.Foreground()
+				}
+				return label.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx C) D {
+				d := layout.Inset{Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+					label := material.Body2(th, closeText)
+					label.Color = app.Comment()
+					if dirty {
+						label.Color = app.Yellow()
+					}
+					return label.Layout(gtx)
+				})
+				closeW = d.Size.X + gtx.Dp(inset.Right)
+				return d
+			}),
+		)
+	})
+	call := m.Stop()
+
+	rect := image.Rectangle{Max: dims.Size}
+	if i == t.active {
+		paint.FillShape(gtx.Ops, app.Surface(), clip.Rect(rect).Op())
+		bar := image.Rect(0, dims.Size.Y-gtx.Dp(unit.Dp(2)), dims.Size.X, dims.Size.Y)
+		paint.FillShape(gtx.Ops, app.Accent(), clip.Rect(bar).Op())
+	}
+	call.Add(gtx.Ops)
+	// Divider on the right
+	div := image.Rect(dims.Size.X-1, gtx.Dp(unit.Dp(6)), dims.Size.X, dims.Size.Y-gtx.Dp(unit.Dp(6)))
+	paint.FillShape(gtx.Ops, app.Selection(), clip.Rect(div).Op())
+	return dims, closeW
+}
@@ -22,6 +22,7 @@ type Toolbar struct {
 	vaultClick    widget.Clickable
 	pickerClick   widget.Clickable
 	themeClick    widget.Clickable
+	searchClick   widget.Clickable
 	filesClick    widget.Clickable // Mobile nav: show files
 	editorClick   widget.Clickable // Mobile nav: show editor
 	pathEditor    widget.Editor
@@ -138,6 +139,20 @@ func (t *Toolbar) layoutButtons(gtx C, th *material.Theme) D {
 				})
 			})
 		}),
+		// Full-text search
+		layout.Rigid(func(gtx C) D {
+			return t.searchClick.Layout(gtx, func(gtx C) D {
+				return layout.Inset{
+					Top:    unit.Dp(6),
+					Bottom: unit.Dp(6),
+					Left:   unit.Dp(8),
+				}.Layout(gtx, func(gtx C) D {
+					label := material.Body2(th, "[Search]")
+					label.Color = app.Comment()
+					return label.Layout(gtx)
+				})
+			})
+		}),
 		// File picker button (native dialog)
 		layout.Rigid(func(gtx C) D {
 			return t.pickerClick.Layout(gtx, func(gtx C) D {
@@ -196,6 +211,11 @@ func (t *Toolbar) EditorClicked(gtx C) bool {
 	return t.editorClick.Clicked(gtx)
 }
 
+// SearchClicked returns true if the Search button was clicked
+func (t *Toolbar) SearchClicked(gtx C) bool {
+	return t.searchClick.Clicked(gtx)
+}
+
 // LayoutMobileNav renders the mobile bottom navigation bar
 func (t *Toolbar) LayoutMobileNav(gtx C, th *material.Theme, showingEditor bool) D {
 	// Handle picker click
@@ -246,6 +266,16 @@ func (t *Toolbar) LayoutMobileNav(gtx C, th *material.Theme, showingEditor bool)
 					})
 				})
 			}),
+			// Search tab
+			layout.Flexed(1, func(gtx C) D {
+				return t.searchClick.Layout(gtx, func(gtx C) D {
+					return layout.Center.Layout(gtx, func(gtx C) D {
+						label := material.Body1(th, "Search")
+						label.Color = app.Comment()
+						return label.Layout(gtx)
+					})
+				})
+			}),
 			// Open vault
 			layout.Flexed(1, func(gtx C) D {
 				return t.pickerClick.Layout(gtx, func(gtx C) D {
@@ -0,0 +1,277 @@
+package trash
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"giopad/app"
+	"giopad/fs"
+	"giopad/ui/dialog"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// purgeChoices are the auto-purge ages the header button cycles through;
+// 0 keeps items forever
+var purgeChoices = []int{7, 30, 90, 0}
+
+// row holds the buttons of one trashed item
+type row struct {
+	restore widget.Clickable
+	purge   widget.Clickable
+}
+
+// View lists what was deleted from the vault and lets the user restore
+// items or purge them for good
+type View struct {
+	trash  *fs.Trash
+	modal  *dialog.Dialog
+	items  []fs.TrashItem
+	rows   map[string]*row
+	err    error
+	active bool
+
+	emptyClick widget.Clickable
+	ageClick   widget.Clickable
+	closeClick widget.Clickable
+	list       widget.List
+
+	onRestore func(path string, item fs.TrashItem)
+}
+
+// New creates a trash view. onRestore runs with the new path of every
+// restored item.
+func New(modal *dialog.Dialog, onRestore func(path string, item fs.TrashItem)) *View {
+	v := &View{
+		modal:     modal,
+		rows:      make(map[string]*row),
+		onRestore: onRestore,
+	}
+	v.list.Axis = layout.Vertical
+	return v
+}
+
+// Open shows the given trash
+func (v *View) Open(trash *fs.Trash) {
+	v.trash = trash
+	v.items = trash.Items()
+	v.list.Position = layout.Position{}
+	v.err = nil
+	v.active = true
+}
+
+// Active returns true while the view is open
+func (v *View) Active() bool {
+	return v.active
+}
+
+// Close hides the view
+func (v *View) Close() {
+	v.active = false
+	v.trash = nil
+	v.items = nil
+}
+
+func (v *View) row(id string) *row {
+	if r, ok := v.rows[id]; ok {
+		return r
+	}
+	r := new(row)
+	v.rows[id] = r
+	return r
+}
+
+// refresh re-reads the items after a change and keeps the error, if any
+func (v *View) refresh(err error) {
+	v.err = err
+	v.items = v.trash.Items()
+}
+
+func (v *View) restore(item fs.TrashItem) {
+	path, err := v.trash.Restore(item.ID)
+	v.refresh(err)
+	if err == nil && v.onRestore != nil {
+		v.onRestore(path, item)
+	}
+}
+
+func (v *View) purge(item fs.TrashItem) {
+	v.modal.Show("Delete permanently", "Delete "+item.Name+" for good? This cannot be undone.",
+		[]string{"Delete", "Cancel"}, func(choice int) {
+			if choice == 0 && v.trash != nil {
+				v.refresh(v.trash.Purge(item.ID))
+			}
+		})
+}
+
+func (v *View) empty() {
+	if len(v.items) == 0 {
+		return
+	}
+	message := fmt.Sprintf("Delete all %d items in the trash for good? This cannot be undone.", len(v.items))
+	v.modal.Show("Empty trash", message, []string{"Empty trash", "Cancel"}, func(choice int) {
+		if choice == 0 && v.trash != nil {
+			v.refresh(v.trash.Empty())
+		}
+	})
+}
+
+// cycleAge moves the auto-purge age to the next choice
+func (v *View) cycleAge() {
+	next := purgeChoices[0]
+	for i, days := range purgeChoices {
+		if days == v.trash.PurgeAfterDays() {
+			next = purgeChoices[(i+1)%len(purgeChoices)]
+		}
+	}
+	v.err = v.trash.SetPurgeAfterDays(next)
+}
+
+func ageLabel(days int) string {
+	if days <= 0 {
+		return "[Auto-purge: never]"
+	}
+	return "[Auto-purge: " + strconv.Itoa(days) + " days]"
+}
+
+// Layout renders the header and the item list
+func (v *View) Layout(gtx C, th *material.Theme) D {
+	if !v.active {
+		return D{}
+	}
+
+	for _, item := range v.items {
+		r := v.row(item.ID)
+		if r.restore.Clicked(gtx) {
+			v.restore(item)
+		}
+		if r.purge.Clicked(gtx) {
+			v.purge(item)
+		}
+	}
+	if v.emptyClick.Clicked(gtx) {
+		v.empty()
+	}
+	if v.ageClick.Clicked(gtx) {
+		v.cycleAge()
+	}
+	if v.closeClick.Clicked(gtx) {
+		v.Close()
+		return D{Size: gtx.Constraints.Min}
+	}
+
+	return layout.Inset{
+		Top:    unit.Dp(16),
+		Left:   unit.Dp(24),
+		Right:  unit.Dp(24),
+		Bottom: unit.Dp(16),
+	}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return v.layoutHeader(gtx, th)
+			}),
+			layout.Flexed(1, func(gtx C) D {
+				if len(v.items) == 0 {
+					label := material.Body2(th, "The trash is empty")
+					label.Color = app.Comment()
+					return label.Layout(gtx)
+				}
+				return material.List(th, &v.list).Layout(gtx, len(v.items), func(gtx C, i int) D {
+					return v.layoutItem(gtx, th, v.items[i])
+				})
+			}),
+		)
+	})
+}
+
+func (v *View) layoutHeader(gtx C, th *material.Theme) D {
+	return layout.Inset{Bottom: unit.Dp(12)}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+					layout.Flexed(1, func(gtx C) D {
+						label := material.Body1(th, "Trash")
+						label.Color = app.Foreground()
+						label.Font.Weight = font.Bold
+						return label.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						return layoutAction(gtx, th, &v.ageClick, ageLabel(v.trash.PurgeAfterDays()), app.Comment())
+					}),
+					layout.Rigid(func(gtx C) D {
+						return layoutAction(gtx, th, &v.emptyClick, "[Empty trash]", app.Red())
+					}),
+					layout.Rigid(func(gtx C) D {
+						return layoutAction(gtx, th, &v.closeClick, "[Close]", app.Comment())
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx C) D {
+				text := "Deleted notes are kept in .trash at the vault root"
+				label := material.Caption(th, text)
+				label.Color = app.Comment()
+				if v.err != nil {
+					label.Text = v.err.Error()
+					label.Color = app.Red()
+				}
+				return label.Layout(gtx)
+			}),
+		)
+	})
+}
+
+// layoutItem renders one trashed item with where and when it was deleted
+func (v *View) layoutItem(gtx C, th *material.Theme, item fs.TrashItem) D {
+	r := v.row(item.ID)
+	return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+			layout.Flexed(1, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx C) D {
+						name := item.Name
+						col := app.Foreground()
+						if item.IsDir {
+							name += "/"
+							col = app.Blue()
+						}
+						label := material.Body2(th, name)
+						label.Color = col
+						return label.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						label := material.Caption(th, item.Path+" · deleted "+item.Deleted.Format("Jan 2 15:04"))
+						label.Color = app.Comment()
+						return label.Layout(gtx)
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx C) D {
+				return layoutAction(gtx, th, &r.restore, "[Restore]", app.Accent())
+			}),
+			layout.Rigid(func(gtx C) D {
+				return layoutAction(gtx, th, &r.purge, "[Delete]", app.Red())
+			}),
+		)
+	})
+}
+
+// layoutAction renders a bracketed text button
+func layoutAction(gtx C, th *material.Theme, click *widget.Clickable, text string, col color.NRGBA) D {
+	return click.Layout(gtx, func(gtx C) D {
+		return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4), Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+			label := material.Body2(th, text)
+			label.Color = col
+			return label.Layout(gtx)
+		})
+	})
+}
@@ -0,0 +1,302 @@
+package tree
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"giopad/fs"
+	"giopad/internal/location"
+)
+
+// nodeAction is one entry of the node context menu
+type nodeAction struct {
+	label string
+	run   func(node *fs.Node)
+}
+
+// showMenu opens the context menu for node
+func (t *Tree) showMenu(node *fs.Node) {
+	if t.modal == nil || t.fsys == nil || node == nil {
+		return
+	}
+	actions := []nodeAction{
+		{"New note", t.newNote},
+		{"New folder", t.newFolder},
+		{"Rename…", t.rename},
+		{"Duplicate", t.duplicate},
+		{"Move to…", t.move},
+		{"Delete…", t.delete},
+	}
+	if node == t.Root {
+		actions = actions[:2] // The vault root itself stays put
+	}
+	if t.OnShowTrash != nil {
+		actions = append(actions, nodeAction{"Show trash", func(*fs.Node) { t.OnShowTrash() }})
+	}
+	labels := make([]string, len(actions))
+	for i, a := range actions {
+		labels[i] = a.label
+	}
+	t.modal.ShowMenu(node.Name, labels, func(choice int) {
+		actions[choice].run(node)
+	})
+}
+
+// selectedNode returns the selected node, or the root if nothing is
+func (t *Tree) selectedNode() *fs.Node {
+	if i := t.selectedIndex(); i >= 0 {
+		return t.flatNodes[i]
+	}
+	return t.Root
+}
+
+// targetDir returns the directory new items go into for node
+func (t *Tree) targetDir(node *fs.Node) string {
+	if node == nil {
+		return t.Root.Path
+	}
+	if node.IsDir {
+		return node.Path
+	}
+	return fs.ParentPath(t.Root, node.Path)
+}
+
+// refresh lists dir again and shows its contents
+func (t *Tree) refresh(dir string) {
+	if t.loader != nil {
+		t.loader.Rescan(t.Root, dir)
+	}
+	t.Expanded[dir] = true
+}
+
+// moved carries expansion, selection and the editor over to newPath
+func (t *Tree) moved(oldPath, newPath string) {
+	for path, open := range t.Expanded {
+		if p, ok := fs.Rebase(path, oldPath, newPath); ok {
+			delete(t.Expanded, path)
+			t.Expanded[p] = open
+		}
+	}
+	if p, ok := fs.Rebase(t.Selected, oldPath, newPath); ok {
+		t.Selected = p
+	}
+	if t.OnRenamed != nil {
+		t.OnRenamed(oldPath, newPath)
+	}
+}
+
+func (t *Tree) showError(title string, err error) {
+	t.modal.Show(title, err.Error(), []string{"OK"}, nil)
+}
+
+// validName rejects names that would escape their directory
+func validName(name string) error {
+	switch {
+	case name == "", name == ".", name == "..":
+		return errors.New("name is empty")
+	case strings.ContainsAny(name, `/\`):
+		return errors.New("name cannot contain / or \\")
+	}
+	return nil
+}
+
+// create prompts for a name and makes a note or folder in node's directory
+func (t *Tree) create(node *fs.Node, isDir bool) {
+	dir := t.targetDir(node)
+	title, name := "New note", "Untitled.md"
+	if isDir {
+		title, name = "New folder", "New folder"
+	}
+	t.modal.ShowPrompt(title, "", name, []string{"Create", "Cancel"}, func(choice int, name string) {
+		if choice != 0 {
+			return
+		}
+		name = strings.TrimSpace(name)
+		if err := validName(name); err != nil {
+			t.showError("Could not create "+strings.ToLower(title), err)
+			return
+		}
+		if !isDir && !location.IsMaybeMarkdown(name) {
+			name += ".md"
+		}
+		path, err := t.fsys.Create(dir, name, isDir)
+		if err != nil {
+			t.showError("Could not create "+strings.ToLower(title), err)
+			return
+		}
+		t.refresh(dir)
+		t.Selected = path
+	})
+}
+
+func (t *Tree) newNote(node *fs.Node) {
+	t.create(node, false)
+}
+
+func (t *Tree) newFolder(node *fs.Node) {
+	t.create(node, true)
+}
+
+// rename prompts for a new name for node
+func (t *Tree) rename(node *fs.Node) {
+	t.modal.ShowPrompt("Rename", "", node.Name, []string{"Rename", "Cancel"}, func(choice int, name string) {
+		name = strings.TrimSpace(name)
+		if choice != 0 || name == node.Name {
+			return
+		}
+		if err := validName(name); err != nil {
+			t.showError("Could not rename", err)
+			return
+		}
+		parent := fs.ParentPath(t.Root, node.Path)
+		newPath, err := t.fsys.Rename(node.Path, name)
+		if err != nil {
+			t.showError("Could not rename", err)
+			return
+		}
+		t.refresh(parent)
+		t.moved(node.Path, newPath)
+	})
+}
+
+// duplicate copies node next to itself and selects the copy
+func (t *Tree) duplicate(node *fs.Node) {
+	parent := fs.ParentPath(t.Root, node.Path)
+	newPath, err := fs.Duplicate(t.fsys, node.Path, parent)
+	if err != nil {
+		t.showError("Could not duplicate", err)
+		return
+	}
+	t.refresh(parent)
+	t.Selected = newPath
+}
+
+// move offers every other folder of the vault as a destination for node.
+// The folders are listed in the background, as the tree may not have
+// loaded them yet.
+func (t *Tree) move(node *fs.Node) {
+	parent := fs.ParentPath(t.Root, node.Path)
+	root, vfs, path := t.Root, t.fsys, node.Path
+	t.moveGen++
+	gen := t.moveGen
+	t.modal.Show("Move "+node.Name, "Looking for folders…", []string{"Cancel"}, func(int) {
+		t.moveGen++
+	})
+	go func() {
+		dests := listFolders(vfs, root, path, parent)
+		t.ready <- func() {
+			if gen != t.moveGen || t.Root != root {
+				return // Cancelled, or another vault opened meanwhile
+			}
+			t.showMoveMenu(node, parent, dests)
+		}
+		if t.Invalidate != nil {
+			t.Invalidate()
+		}
+	}()
+}
+
+// folder is a destination offered by move
+type folder struct {
+	path  string
+	label string // name, indented by depth
+}
+
+// listFolders walks the vault at root for every folder path can move
+// into: not path itself or anything below it, nor parent, the folder it
+// is already in. Hidden folders are skipped.
+func listFolders(vfs fs.VaultFS, root *fs.Node, path, parent string) []folder {
+	var dests []folder
+	var walk func(dir, name string, depth int)
+	walk = func(dir, name string, depth int) {
+		if dir == path {
+			return // Not into itself or its own subfolders
+		}
+		if dir != parent {
+			dests = append(dests, folder{path: dir, label: strings.Repeat("   ", depth) + name})
+		}
+		entries, err := vfs.List(dir)
+		if err != nil {
+			return // Still a destination; just nothing listed below it
+		}
+		slices.SortFunc(entries, func(a, b fs.Entry) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+		for _, entry := range entries {
+			if entry.IsDir && !strings.HasPrefix(entry.Name, ".") {
+				walk(entry.Path, entry.Name, depth+1)
+			}
+		}
+	}
+	walk(root.Path, root.Name, root.Depth)
+	return dests
+}
+
+// showMoveMenu offers dests for node and moves it to the one picked
+func (t *Tree) showMoveMenu(node *fs.Node, parent string, dests []folder) {
+	if len(dests) == 0 {
+		t.showError("Could not move", errors.New("there is no other folder to move to"))
+		return
+	}
+
+	labels := make([]string, len(dests))
+	for i, d := range dests {
+		labels[i] = d.label
+	}
+	t.modal.ShowMenu("Move "+node.Name+" to", labels, func(choice int) {
+		dest := dests[choice].path
+		newPath, err := t.fsys.Move(node.Path, dest)
+		if err != nil {
+			t.showError("Could not move", err)
+			return
+		}
+		t.refresh(parent)
+		t.refresh(dest)
+		t.moved(node.Path, newPath)
+	})
+}
+
+// delete asks for confirmation, then moves node to the vault trash, or
+// removes it for good if the vault has no trash
+func (t *Tree) delete(node *fs.Node) {
+	title, message, button := "Move to trash", "Move "+node.Name+" to the trash?", "Move to trash"
+	if t.trash == nil {
+		title, message, button = "Delete", "Delete "+node.Name+"?", "Delete"
+		if node.IsDir {
+			message = "Delete " + node.Name + " and everything in it?"
+		}
+	}
+	t.modal.Show(title, message, []string{button, "Cancel"}, func(choice int) {
+		if choice != 0 {
+			return
+		}
+		if t.BeforeDelete != nil {
+			t.BeforeDelete(node.Path, func() { t.remove(node) })
+			return
+		}
+		t.remove(node)
+	})
+}
+
+// remove moves node to the vault trash, or deletes it if there is none
+func (t *Tree) remove(node *fs.Node) {
+	parent := fs.ParentPath(t.Root, node.Path)
+	var err error
+	if t.trash != nil {
+		err = t.trash.Put(node.Path, fs.RelPath(t.Root, node.Path), node.IsDir)
+	} else {
+		err = t.fsys.Delete(node.Path)
+	}
+	if err != nil {
+		t.showError("Could not delete", err)
+		return
+	}
+	t.refresh(parent)
+	if _, ok := fs.Rebase(t.Selected, node.Path, node.Path); ok {
+		t.Selected = parent
+	}
+	if t.OnDeleted != nil {
+		t.OnDeleted(node.Path)
+	}
+}
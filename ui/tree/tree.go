@@ -2,17 +2,23 @@ package tree
 
 import (
 	"image"
+	"time"
 
-	"gioui.org/io/key"
+	"gioui.org/f32"
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
 	"gioui.org/layout"
+	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/unit"
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 
+	"giopad/action"
 	"giopad/app"
 	"giopad/fs"
+	"giopad/ui/dialog"
 )
 
 type (
@@ -27,9 +33,31 @@ type Tree struct {
 	Selected string
 	Focused  bool
 
+	// OnRenamed is called after a rename or move with the old and new path
+	OnRenamed func(oldPath, newPath string)
+	// BeforeDelete, if set, is called once a delete of path is confirmed.
+	// It runs proceed to go ahead, or drops it to cancel.
+	BeforeDelete func(path string, proceed func())
+	// OnDeleted is called after path was deleted
+	OnDeleted func(path string)
+	// OnShowTrash, if set, adds "Show trash" to the context menu
+	OnShowTrash func()
+	// Invalidate, if set, asks for a frame once a background listing for
+	// a dialog is ready
+	Invalidate func()
+
+	fsys      fs.VaultFS
+	loader    *fs.Loader
+	trash     *fs.Trash
+	modal     *dialog.Dialog
 	list      widget.List
 	clicks    map[string]*widget.Clickable
-	flatNodes []*fs.Node // cached for keyboard nav
+	presses   map[string]*press
+	pickFirst bool        // select the first entry once the root is listed
+	flatNodes []*fs.Node  // cached for keyboard nav
+	anchor    string      // path of the first visible row, kept across tree patches
+	ready     chan func() // work to finish on the UI goroutine after a listing
+	moveGen   int         // bumped to drop a move menu still being listed
 }
 
 // New creates a new Tree widget
@@ -37,18 +65,53 @@ func New() *Tree {
 	return &Tree{
 		Expanded: make(map[string]bool),
 		clicks:   make(map[string]*widget.Clickable),
+		presses:  make(map[string]*press),
+		ready:    make(chan func(), 4),
 	}
 }
 
-// SetRoot sets the root node and rescans
+// SetFS sets the filesystem the tree's file operations go through
+func (t *Tree) SetFS(vfs fs.VaultFS) {
+	t.fsys = vfs
+}
+
+// SetTrash sets where deleted items go; nil deletes them for good
+func (t *Tree) SetTrash(trash *fs.Trash) {
+	t.trash = trash
+}
+
+// SetDialog sets the dialog used for prompts, menus and confirmations
+func (t *Tree) SetDialog(d *dialog.Dialog) {
+	t.modal = d
+}
+
+// SetRoot sets the root node; its children load on the next frame
 func (t *Tree) SetRoot(root *fs.Node) {
 	t.Root = root
 	// Expand root by default
 	if root != nil {
 		t.Expanded[root.Path] = true
-		// Select first item if nothing selected
-		if t.Selected == "" && len(root.Children) > 0 {
-			t.Selected = root.Children[0].Path
+		t.pickFirst = t.Selected == ""
+	}
+}
+
+// SetLoader sets the loader that lists directories as they are expanded
+func (t *Tree) SetLoader(loader *fs.Loader) {
+	t.loader = loader
+}
+
+// load applies finished listings and starts one for every expanded
+// directory that has not been listed yet
+func (t *Tree) load(nodes []*fs.Node) {
+	if t.loader == nil {
+		return
+	}
+	if !t.Root.Loaded {
+		t.loader.Load(t.Root)
+	}
+	for _, n := range nodes {
+		if n.IsDir && !n.Loaded && t.Expanded[n.Path] {
+			t.loader.Load(n)
 		}
 	}
 }
@@ -69,79 +132,116 @@ func (t *Tree) Layout(gtx C, th *material.Theme) D {
 		return material.Body1(th, "No vault loaded").Layout(gtx)
 	}
 
+	if t.loader != nil {
+		t.loader.Apply()
+	}
+	for len(t.ready) > 0 {
+		(<-t.ready)()
+	}
+	// Select first item if nothing selected
+	if t.pickFirst && t.Root.Loaded {
+		t.pickFirst = false
+		if t.Selected == "" && len(t.Root.Children) > 0 {
+			t.Selected = t.Root.Children[0].Path
+		}
+	}
+
 	nodes := fs.FlattenTree(t.Root, t.Expanded)
 	t.flatNodes = nodes // cache for keyboard nav
-
-	// Handle keyboard navigation when focused
-	if t.Focused {
-		t.handleKeys(gtx)
+	t.load(nodes)
+	if len(nodes) == 0 && t.Root.Loading {
+		label := material.Body1(th, "Loading vault…")
+		label.Color = app.Comment()
+		return label.Layout(gtx)
 	}
 
 	t.list.Axis = layout.Vertical
+	t.restoreAnchor(nodes)
 
-	return material.List(th, &t.list).Layout(gtx, len(nodes), func(gtx C, i int) D {
+	dims := material.List(th, &t.list).Layout(gtx, len(nodes), func(gtx C, i int) D {
 		node := nodes[i]
 		return t.layoutNode(gtx, th, node)
 	})
+
+	if first := t.list.Position.First; first < len(nodes) {
+		t.anchor = nodes[first].Path
+	}
+	return dims
 }
 
-func (t *Tree) handleKeys(gtx C) {
-	for {
-		ev, ok := gtx.Event(
-			key.Filter{Name: key.NameDownArrow},
-			key.Filter{Name: key.NameUpArrow},
-			key.Filter{Name: key.NameReturn},
-			key.Filter{Name: key.NameSpace},
-			key.Filter{Name: key.NameRightArrow},
-			key.Filter{Name: key.NameLeftArrow},
-		)
-		if !ok {
-			break
-		}
-		e, ok := ev.(key.Event)
-		if !ok || e.State != key.Press {
-			continue
+// restoreAnchor keeps the first visible row in place when rows above it
+// were added or removed since the last frame
+func (t *Tree) restoreAnchor(nodes []*fs.Node) {
+	first := t.list.Position.First
+	if t.anchor == "" || (first < len(nodes) && nodes[first].Path == t.anchor) {
+		return
+	}
+	for i, n := range nodes {
+		if n.Path == t.anchor {
+			t.list.Position.First = i
+			return
 		}
+	}
+}
 
-		idx := t.selectedIndex()
-
-		switch e.Name {
-		case key.NameDownArrow:
-			if idx < len(t.flatNodes)-1 {
+// Actions returns the keyboard commands of the tree. They only run while
+// the tree has focus.
+func (t *Tree) Actions() []action.Action {
+	focused := func() bool { return t.Focused }
+	// op runs a file operation on the selected node; most skip the root
+	op := func(run func(*fs.Node), onRoot bool) func() {
+		return func() {
+			node := t.selectedNode()
+			if t.fsys == nil || t.modal == nil || node == nil || (node == t.Root && !onRoot) {
+				return
+			}
+			run(node)
+		}
+	}
+	return []action.Action{
+		{ID: "tree.down", Title: "Tree: next item", Keys: action.Keys("Down"), When: focused, Hidden: true, Run: func() {
+			if idx := t.selectedIndex(); idx < len(t.flatNodes)-1 {
 				t.Selected = t.flatNodes[idx+1].Path
 			}
-		case key.NameUpArrow:
-			if idx > 0 {
+		}},
+		{ID: "tree.up", Title: "Tree: previous item", Keys: action.Keys("Up"), When: focused, Hidden: true, Run: func() {
+			if idx := t.selectedIndex(); idx > 0 {
 				t.Selected = t.flatNodes[idx-1].Path
 			}
-		case key.NameReturn, key.NameSpace:
-			// Toggle dir or select file
-			if idx >= 0 && idx < len(t.flatNodes) {
-				node := t.flatNodes[idx]
-				if node.IsDir {
-					t.Expanded[node.Path] = !t.Expanded[node.Path]
-				}
+		}},
+		{ID: "tree.toggle", Title: "Tree: toggle folder", Keys: action.Keys("Enter", "Space"), When: focused, Hidden: true, Run: func() {
+			if node := t.selectedFlat(); node != nil && node.IsDir {
+				t.Expanded[node.Path] = !t.Expanded[node.Path]
 			}
-		case key.NameRightArrow:
-			// Expand directory
-			if idx >= 0 && idx < len(t.flatNodes) {
-				node := t.flatNodes[idx]
-				if node.IsDir {
-					t.Expanded[node.Path] = true
-				}
+		}},
+		{ID: "tree.expand", Title: "Tree: expand folder", Keys: action.Keys("Right"), When: focused, Hidden: true, Run: func() {
+			if node := t.selectedFlat(); node != nil && node.IsDir {
+				t.Expanded[node.Path] = true
 			}
-		case key.NameLeftArrow:
-			// Collapse directory
-			if idx >= 0 && idx < len(t.flatNodes) {
-				node := t.flatNodes[idx]
-				if node.IsDir && t.Expanded[node.Path] {
-					t.Expanded[node.Path] = false
-				}
+		}},
+		{ID: "tree.collapse", Title: "Tree: collapse folder", Keys: action.Keys("Left"), When: focused, Hidden: true, Run: func() {
+			if node := t.selectedFlat(); node != nil && node.IsDir {
+				t.Expanded[node.Path] = false
 			}
-		}
+		}},
+		{ID: "tree.newNote", Title: "New note", Keys: action.Keys("Ctrl+N"), When: focused, Run: op(t.newNote, true)},
+		{ID: "tree.newFolder", Title: "New folder", Keys: action.Keys("Ctrl+Shift+N"), When: focused, Run: op(t.newFolder, true)},
+		{ID: "tree.rename", Title: "Rename…", Keys: action.Keys("F2"), When: focused, Run: op(t.rename, false)},
+		{ID: "tree.duplicate", Title: "Duplicate", Keys: action.Keys("Ctrl+Shift+D"), When: focused, Run: op(t.duplicate, false)},
+		{ID: "tree.move", Title: "Move to…", Keys: action.Keys("Ctrl+M"), When: focused, Run: op(t.move, false)},
+		{ID: "tree.delete", Title: "Delete…", Keys: action.Keys("Delete"), When: focused, Run: op(t.delete, false)},
+		{ID: "tree.menu", Title: "Tree: context menu", Keys: action.Keys("Shift+F10"), When: focused, Hidden: true, Run: op(t.showMenu, true)},
 	}
 }
 
+// selectedFlat returns the selected node among the visible rows
+func (t *Tree) selectedFlat() *fs.Node {
+	if idx := t.selectedIndex(); idx >= 0 {
+		return t.flatNodes[idx]
+	}
+	return nil
+}
+
 func (t *Tree) selectedIndex() int {
 	for i, n := range t.flatNodes {
 		if n.Path == t.Selected {
@@ -153,10 +253,18 @@ func (t *Tree) selectedIndex() int {
 
 func (t *Tree) layoutNode(gtx C, th *material.Theme, node *fs.Node) D {
 	click := t.clickable(node.Path)
+	pr := t.press(node.Path)
+	if t.handlePress(gtx, pr) {
+		t.Selected = node.Path
+		t.showMenu(node)
+	}
 
 	// Handle clicks
 	if click.Clicked(gtx) {
-		if node.IsDir {
+		if pr.fired {
+			// The release ending a long press is not a click
+			pr.fired = false
+		} else if node.IsDir {
 			// Toggle expansion
 			t.Expanded[node.Path] = !t.Expanded[node.Path]
 		} else {
@@ -171,6 +279,17 @@ func (t *Tree) layoutNode(gtx C, th *material.Theme, node *fs.Node) D {
 	// Selection highlight
 	isSelected := t.Selected == node.Path
 
+	// Record the row so the press area can wrap it once its size is known
+	macro := op.Record(gtx.Ops)
+	dims := t.layoutRow(gtx, th, node, click, indent, isSelected)
+	call := macro.Stop()
+	defer clip.Rect{Max: dims.Size}.Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, pr)
+	call.Add(gtx.Ops)
+	return dims
+}
+
+func (t *Tree) layoutRow(gtx C, th *material.Theme, node *fs.Node, click *widget.Clickable, indent unit.Dp, isSelected bool) D {
 	return click.Layout(gtx, func(gtx C) D {
 		return layout.Stack{}.Layout(gtx,
 			// Background (for selection)
@@ -195,14 +314,22 @@ func (t *Tree) layoutNode(gtx C, th *material.Theme, node *fs.Node) D {
 							icon := t.nodeIcon(node)
 							label := material.Body2(th, icon)
 							label.Color = app.Comment()
+							if len(node.Conflicts) > 0 || node.ConflictOf != "" {
+								label.Color = app.Yellow()
+							}
 							return layout.Inset{Right: unit.Dp(6)}.Layout(gtx, label.Layout)
 						}),
 						// Name
 						layout.Flexed(1, func(gtx C) D {
-							label := material.Body2(th, node.Name)
-							if node.IsDir {
+							label := material.Body2(th, nodeLabel(node))
+							switch {
+							case node.Err != nil:
+								label.Color = app.Red()
+							case node.IsDir:
 								label.Color = app.Blue()
-							} else {
+							case node.ConflictOf != "":
+								label.Color = app.Yellow()
+							default:
 								label.Color = app.Foreground()
 							}
 							return label.Layout(gtx)
@@ -214,17 +341,110 @@ func (t *Tree) layoutNode(gtx C, th *material.Theme, node *fs.Node) D {
 	})
 }
 
+// longPress is how long a touch must be held to open the context menu
+const longPress = 500 * time.Millisecond
+
+// press tracks a pending right-click or long press on one row
+type press struct {
+	armed bool
+	at    time.Time
+	pos   f32.Point
+	fired bool // a long press opened the menu; swallow the following click
+}
+
+func (t *Tree) press(path string) *press {
+	if p, ok := t.presses[path]; ok {
+		return p
+	}
+	p := new(press)
+	t.presses[path] = p
+	return p
+}
+
+// handlePress reports whether the row asked for its context menu, either by
+// a secondary click or a touch held in place for longPress
+func (t *Tree) handlePress(gtx C, p *press) bool {
+	menu := false
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target: p,
+			Kinds:  pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel,
+		})
+		if !ok {
+			break
+		}
+		e, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+		switch e.Kind {
+		case pointer.Press:
+			p.fired = false
+			if e.Buttons.Contain(pointer.ButtonSecondary) {
+				menu = true
+			} else if e.Source == pointer.Touch {
+				p.armed, p.at, p.pos = true, gtx.Now, e.Position
+			}
+		case pointer.Drag:
+			slop := float32(gtx.Dp(unit.Dp(8)))
+			if d := e.Position.Sub(p.pos); d.X*d.X+d.Y*d.Y > slop*slop {
+				p.armed = false
+			}
+		case pointer.Release, pointer.Cancel:
+			p.armed = false
+		}
+	}
+	if p.armed {
+		if due := p.at.Add(longPress); !gtx.Now.Before(due) {
+			p.armed, p.fired = false, true
+			menu = true
+		} else {
+			gtx.Execute(op.InvalidateCmd{At: due})
+		}
+	}
+	return menu
+}
+
 func (t *Tree) nodeIcon(node *fs.Node) string {
+	if node.IsDir && node.Loading && t.Expanded[node.Path] {
+		return "…" // Listing in the background
+	}
 	if node.IsDir {
 		if t.Expanded[node.Path] {
 			return "▼"
 		}
 		return "▶"
 	}
+	if node.ConflictOf != "" {
+		return "⇄"
+	}
+	if len(node.Conflicts) > 0 {
+		return "!"
+	}
 	return "•"
 }
 
-// SelectedPath returns the currently selected file path
+// nodeLabel returns the display name; conflict copies show when and where
+// they came from instead of their long file name
+func nodeLabel(node *fs.Node) string {
+	if node.Err != nil {
+		return node.Name + " (unreadable)"
+	}
+	if node.ConflictOf == "" {
+		return node.Name
+	}
+	info, ok := fs.ParseConflictName(node.Name)
+	if !ok {
+		return node.Name
+	}
+	return "conflict · " + info.Time.Format("Jan 2 15:04") + " · " + info.Device
+}
+
+// SelectedPath returns the currently selected file path, or "" while a
+// directory is selected
 func (t *Tree) SelectedPath() string {
+	if i := t.selectedIndex(); i >= 0 && t.flatNodes[i].IsDir {
+		return ""
+	}
 	return t.Selected
 }